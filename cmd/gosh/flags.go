@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const usage = "usage: gosh [-cenx] [--norc] [--rcfile file] [script | -c command [name]] [args ...]"
+
+// flags holds the command line options of the shell
+type flags struct {
+	command bool // -c, the commands are read from the first argument rather than from a script
+	noexec  bool // -n, the commands are only checked for syntax errors, never run
+	errexit bool // -e, as with set -e
+	xtrace  bool // -x, as with set -x
+
+	norc   bool   // --norc, the goshrc is not run at startup
+	rcfile string // --rcfile, the file run at startup instead of the goshrc
+
+	args []string // the arguments following the options: the script or the command, then $0 for -c, then the positional parameters
+}
+
+// parseFlags parses the options preceding the first argument, such as -ex or --rcfile file, + instead of - turning an option off
+// Everything from the first argument on is left to the script, as is everything following --
+func parseFlags(args []string) (flags, error) {
+	var f flags
+
+loop:
+	for len(args) > 0 {
+		arg := args[0]
+
+		switch {
+		case arg == "--":
+			args = args[1:]
+			break loop
+		case arg == "--norc":
+			f.norc = true
+		case arg == "--rcfile" || strings.HasPrefix(arg, "--rcfile="):
+			if value, found := strings.CutPrefix(arg, "--rcfile="); found {
+				f.rcfile = value
+				break
+			}
+
+			if len(args) < 2 {
+				return f, fmt.Errorf("--rcfile: option requires an argument")
+			}
+			f.rcfile = args[1]
+			args = args[1:]
+		case strings.HasPrefix(arg, "--"):
+			return f, fmt.Errorf("%s: invalid option", arg)
+		case len(arg) > 1 && (arg[0] == '-' || arg[0] == '+'):
+			enable := arg[0] == '-'
+
+			for _, flag := range arg[1:] {
+				switch flag {
+				case 'c':
+					f.command = enable
+				case 'n':
+					f.noexec = enable
+				case 'e':
+					f.errexit = enable
+				case 'x':
+					f.xtrace = enable
+				default:
+					return f, fmt.Errorf("%c%c: invalid option", arg[0], flag)
+				}
+			}
+		default:
+			break loop
+		}
+
+		args = args[1:]
+	}
+
+	if f.command && len(args) == 0 {
+		return f, fmt.Errorf("-c: option requires an argument")
+	}
+	f.args = args
+
+	return f, nil
+}
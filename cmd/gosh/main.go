@@ -1,17 +1,28 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/SebastianRichiteanu/Gosh/internal/autocompleter"
 	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
 	"github.com/SebastianRichiteanu/Gosh/internal/closer"
+	"github.com/SebastianRichiteanu/Gosh/internal/commands"
 	"github.com/SebastianRichiteanu/Gosh/internal/config"
 	"github.com/SebastianRichiteanu/Gosh/internal/executor"
+	"github.com/SebastianRichiteanu/Gosh/internal/jobs"
 	"github.com/SebastianRichiteanu/Gosh/internal/logger"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
 	"github.com/SebastianRichiteanu/Gosh/internal/prompt"
+	"github.com/SebastianRichiteanu/Gosh/internal/script"
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
 	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/variables"
+	"github.com/mattn/go-isatty"
 )
 
 func main() {
@@ -20,10 +31,26 @@ func main() {
 }
 
 func run() int {
+	executor.ReserveFds()
+
+	f, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gosh: %v\n%s\n", err, usage)
+		return 2
+	}
+
+	in, err := openInput(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gosh: %v\n", err)
+		return 127
+	}
+
 	reloadCfgChannel := make(chan bool, 1)
 	aliases := make(types.Aliases)
 
-	cfg, err := config.NewConfig(reloadCfgChannel)
+	vars := variables.NewStore()
+
+	cfg, err := config.NewConfig(reloadCfgChannel, vars)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize config: %v\n", err)
 		return 1
@@ -31,7 +58,30 @@ func run() int {
 
 	exitChannel := make(chan int, 1)
 
-	builtinCmds := builtins.InitBuiltinCmds(exitChannel, reloadCfgChannel, &cfg.HistoryFile, &aliases, &cfg.AliasFile)
+	jobTable := jobs.NewTable(in == nil)
+	options := &types.Options{Errexit: f.errexit, Xtrace: f.xtrace}
+	functions := make(types.Functions)
+	trapTable := traps.NewTable()
+	commandTable := commands.NewTable()
+
+	builtinCmds := builtins.InitBuiltinCmds(exitChannel, reloadCfgChannel, &cfg.HistoryFile, &aliases, &cfg.AliasFile, jobTable, options, &functions, trapTable, commandTable)
+
+	prs := parser.NewParser(&aliases)
+	exec := executor.NewExecutor(&builtinCmds, &functions, commandTable, prs, jobTable, options, vars, trapTable, cfg)
+
+	if in == nil {
+		exec.SetInteractive()
+	}
+
+	// The goshrc runs before the rest of the shell is set up, as it may change the config
+	// Scripts do not run it, so that they behave the same for every user
+	if in == nil && !f.norc {
+		rcfile := cfg.GoshrcFile
+		if f.rcfile != "" {
+			rcfile = f.rcfile
+		}
+		exec.SourceFile(rcfile)
+	}
 
 	log, err := logger.NewLogger(cfg.LogFile, cfg.LogLevel)
 	if err != nil {
@@ -39,31 +89,131 @@ func run() int {
 		return 1
 	}
 
-	ac := autocompleter.NewAutocompleter(&builtinCmds, cfg, log)
-	exec := executor.NewExecutor(&builtinCmds, cfg, log)
+	if in != nil {
+		c := closer.NewCloser(exitChannel, nil, cfg, log, jobTable, trapTable, exec)
+		defer c.Recover()
+		exec.SetCleanup(c.Cleanup)
+		go c.ListenForSignals()
 
-	pr, err := prompt.NewPrompt(&builtinCmds, ac, &aliases, cfg, log)
+		exec.SetArgs(in.name, in.args)
+
+		status := runScript(script.NewReader(in.reader, prs), in.label, exec, f.noexec)
+		exitShell(exitChannel, status)
+		return status
+	}
+
+	ac := autocompleter.NewAutocompleter(&builtinCmds, &functions, commandTable, vars, cfg, log)
+
+	pr, err := prompt.NewPrompt(&builtinCmds, ac, prs, &aliases, cfg, log)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize prompt: %v\n", err)
 		return 1
 	}
 
-	c := closer.NewCloser(exitChannel, pr, cfg, log)
+	exec.SetTerminal(pr)
+
+	c := closer.NewCloser(exitChannel, pr, cfg, log, jobTable, trapTable, exec)
 	defer c.Recover()
+	exec.SetCleanup(c.Cleanup)
 	go c.ListenForSignals()
 
 	log.Info("123")
 
-	return runShellLoop(pr, exec)
+	status := runShellLoop(pr, exec)
+	exitShell(exitChannel, status)
+	return status
+}
+
+// exitShell hands the exit status to the closer, which runs the EXIT trap and terminates the program, the same way exit does
+func exitShell(exitChannel chan int, status int) {
+	exitChannel <- status
+	select {}
+}
+
+// input is where a non-interactive shell reads its commands from
+type input struct {
+	reader io.Reader
+	label  string   // shown before the syntax errors: the path of the script, -c, or nothing for stdin
+	name   string   // $0
+	args   []string // the positional parameters
+}
+
+// openInput opens the script, the -c command or stdin the shell reads its commands from
+// It returns nil when the shell is interactive, reading its commands from the terminal
+func openInput(f flags) (*input, error) {
+	switch {
+	case f.command:
+		in := input{reader: strings.NewReader(f.args[0]), label: "-c", name: os.Args[0]}
+		if len(f.args) > 1 {
+			in.name, in.args = f.args[1], f.args[2:]
+		}
+		return &in, nil
+	case len(f.args) > 0:
+		file, err := os.Open(f.args[0])
+		if err != nil {
+			var pathErr *os.PathError
+			if errors.As(err, &pathErr) {
+				return nil, fmt.Errorf("%s: %v", f.args[0], pathErr.Err)
+			}
+			return nil, err
+		}
+		return &input{reader: bufio.NewReader(file), label: f.args[0], name: f.args[0], args: f.args[1:]}, nil
+	case !isTerminal(os.Stdin):
+		// Stdin is read unbuffered, the commands of the script reading the lines following them
+		return &input{reader: os.Stdin, name: os.Args[0]}, nil
+	}
+
+	return nil, nil
+}
+
+func isTerminal(file *os.File) bool {
+	return isatty.IsTerminal(file.Fd())
+}
+
+// runScript runs the commands of a non-interactive shell one after the other, as they are read
+// It stops at the first syntax error, or after the last command, returning its exit status. With noexec,
+// the commands are only read, which checks their syntax
+func runScript(reader *script.Reader, label string, exec *executor.Executor, noexec bool) int {
+	status := 0
+
+	for {
+		cmd, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return status
+		}
+
+		if err != nil {
+			if label != "" {
+				fmt.Fprintf(os.Stderr, "gosh: %s: %v\n", label, err)
+			} else {
+				fmt.Fprintf(os.Stderr, "gosh: %v\n", err)
+			}
+			return 2
+		}
+
+		if noexec {
+			continue
+		}
+
+		status = exec.Execute(cmd)
+		if exec.Exited() {
+			return status
+		}
+	}
 }
 
 func runShellLoop(pr *prompt.Prompt, exec *executor.Executor) int {
 	var previousInput string
 
 	for {
+		exec.RunPendingTraps()
+		exec.NotifyJobs()
+
 		cmd, newInput, err := pr.HandlePrompt(previousInput)
 		if err != nil {
-			panic(err)
+			fmt.Fprintf(os.Stderr, "gosh: %v\n", err)
+			previousInput = ""
+			continue
 		}
 
 		if len(newInput) != 0 {
@@ -73,6 +223,9 @@ func runShellLoop(pr *prompt.Prompt, exec *executor.Executor) int {
 			previousInput = ""
 		}
 
-		exec.Execute(cmd)
+		status := exec.Execute(cmd)
+		if exec.Exited() {
+			return status
+		}
 	}
 }
@@ -0,0 +1,281 @@
+package arithmetic
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxRecursion limits the evaluation of variables holding expressions which refer to each other
+const maxRecursion = 64
+
+var (
+	errDivisionByZero    = errors.New("division by 0")
+	errNegativeExponent  = errors.New("exponent less than 0")
+	errRecursionExceeded = errors.New("expression recursion level exceeded")
+)
+
+// assignmentOperators are the operators storing their result in the variable on their left
+var assignmentOperators = map[string]string{
+	"=": "", "+=": "+", "-=": "-", "*=": "*", "/=": "/", "%=": "%", "**=": "**",
+	"<<=": "<<", ">>=": ">>", "&=": "&", "^=": "^", "|=": "|",
+}
+
+// binaryPrecedence gives the precedence of the binary operators, the higher binding the tighter
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, "<=": 7, ">": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+	"**": 11,
+}
+
+// Variables gives the evaluator access to the shell variables
+type Variables interface {
+	Lookup(name string) (string, bool)
+	Set(name, value string) error
+}
+
+// Evaluate evaluates an integer arithmetic expression, such as the one of $((...)), and returns its value
+// Variables are referred to by their name, an unset or empty variable being 0 and any other value being
+// evaluated as an expression itself
+func Evaluate(expr string, vars Variables) (int64, error) {
+	e := evaluator{vars: vars}
+
+	value, err := e.evaluate(expr)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", strings.TrimSpace(expr), err)
+	}
+
+	return value, nil
+}
+
+type evaluator struct {
+	vars  Variables
+	depth int
+}
+
+func (e *evaluator) evaluate(expr string) (int64, error) {
+	if e.depth > maxRecursion {
+		return 0, errRecursionExceeded
+	}
+
+	e.depth++
+	defer func() { e.depth-- }()
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(tokens) == 0 {
+		return 0, nil
+	}
+
+	p := parser{tokens: tokens}
+	root, err := p.parseComma()
+	if err == nil && p.pos < len(tokens) {
+		err = p.syntaxError()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return e.eval(root)
+}
+
+func (e *evaluator) eval(n node) (int64, error) {
+	switch n := n.(type) {
+	case numberNode:
+		return n.value, nil
+	case variableNode:
+		return e.variable(n.name)
+	case unaryNode:
+		return e.evalUnary(n)
+	case incrementNode:
+		return e.evalIncrement(n)
+	case binaryNode:
+		return e.evalBinary(n)
+	case assignNode:
+		return e.evalAssign(n)
+	case ternaryNode:
+		condition, err := e.eval(n.condition)
+		if err != nil {
+			return 0, err
+		}
+
+		if condition != 0 {
+			return e.eval(n.then)
+		}
+		return e.eval(n.otherwise)
+	}
+
+	return 0, fmt.Errorf("unknown expression node %T", n)
+}
+
+// variable returns the value of a variable, evaluating its content as an expression
+func (e *evaluator) variable(name string) (int64, error) {
+	value, _ := e.vars.Lookup(name)
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+
+	return e.evaluate(value)
+}
+
+func (e *evaluator) evalUnary(n unaryNode) (int64, error) {
+	operand, err := e.eval(n.operand)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.operator {
+	case "-":
+		return -operand, nil
+	case "+":
+		return operand, nil
+	case "!":
+		return boolToInt(operand == 0), nil
+	case "~":
+		return ^operand, nil
+	}
+
+	return 0, fmt.Errorf("unknown unary operator %s", n.operator)
+}
+
+func (e *evaluator) evalIncrement(n incrementNode) (int64, error) {
+	value, err := e.variable(n.name)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := e.vars.Set(n.name, strconv.FormatInt(value+n.delta, 10)); err != nil {
+		return 0, err
+	}
+
+	if n.prefix {
+		return value + n.delta, nil
+	}
+	return value, nil
+}
+
+func (e *evaluator) evalBinary(n binaryNode) (int64, error) {
+	left, err := e.eval(n.left)
+	if err != nil {
+		return 0, err
+	}
+
+	// The right operand of && and || is only evaluated if needed, as it may assign variables
+	switch n.operator {
+	case "&&":
+		if left == 0 {
+			return 0, nil
+		}
+	case "||":
+		if left != 0 {
+			return 1, nil
+		}
+	}
+
+	right, err := e.eval(n.right)
+	if err != nil {
+		return 0, err
+	}
+
+	return apply(n.operator, left, right)
+}
+
+func (e *evaluator) evalAssign(n assignNode) (int64, error) {
+	value, err := e.eval(n.value)
+	if err != nil {
+		return 0, err
+	}
+
+	if operator := assignmentOperators[n.operator]; operator != "" {
+		current, err := e.variable(n.name)
+		if err != nil {
+			return 0, err
+		}
+
+		if value, err = apply(operator, current, value); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := e.vars.Set(n.name, strconv.FormatInt(value, 10)); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// apply computes the result of a binary operator
+func apply(operator string, left, right int64) (int64, error) {
+	switch operator {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/", "%":
+		if right == 0 {
+			return 0, errDivisionByZero
+		}
+		if operator == "/" {
+			return left / right, nil
+		}
+		return left % right, nil
+	case "**":
+		if right < 0 {
+			return 0, errNegativeExponent
+		}
+
+		result := int64(1)
+		for ; right > 0; right-- {
+			result *= left
+		}
+		return result, nil
+	case "<<":
+		return left << uint64(right&63), nil
+	case ">>":
+		return left >> uint64(right&63), nil
+	case "<":
+		return boolToInt(left < right), nil
+	case "<=":
+		return boolToInt(left <= right), nil
+	case ">":
+		return boolToInt(left > right), nil
+	case ">=":
+		return boolToInt(left >= right), nil
+	case "==":
+		return boolToInt(left == right), nil
+	case "!=":
+		return boolToInt(left != right), nil
+	case "&":
+		return left & right, nil
+	case "^":
+		return left ^ right, nil
+	case "|":
+		return left | right, nil
+	case "&&", "||":
+		return boolToInt(right != 0), nil
+	case ",":
+		return right, nil
+	}
+
+	return 0, fmt.Errorf("unknown operator %s", operator)
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,436 @@
+package arithmetic
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	errOperandExpected = errors.New("syntax error: operand expected")
+	errInvalidNumber   = errors.New("invalid number")
+	errInvalidBase     = errors.New("invalid arithmetic base")
+)
+
+// operators are the operators of an expression, longest first so that the tokenizer matches them greedily
+var operators = []string{
+	"<<=", ">>=", "**=",
+	"**", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+=", "-=", "*=", "/=", "%=", "&=", "^=", "|=",
+	"+", "-", "*", "/", "%", "<", ">", "&", "^", "|", "!", "~", "?", ":", "=", ",", "(", ")",
+}
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenIdentifier
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value int64
+	// rest is the input starting at the token, shown in syntax errors
+	rest string
+}
+
+type node any
+
+type numberNode struct {
+	value int64
+}
+
+type variableNode struct {
+	name string
+}
+
+type unaryNode struct {
+	operator string
+	operand  node
+}
+
+// incrementNode is a ++ or -- applied to a variable, before or after it
+type incrementNode struct {
+	name   string
+	delta  int64
+	prefix bool
+}
+
+type binaryNode struct {
+	operator    string
+	left, right node
+}
+
+type assignNode struct {
+	name     string
+	operator string
+	value    node
+}
+
+type ternaryNode struct {
+	condition, then, otherwise node
+}
+
+// tokenize splits an expression into numbers, variable names and operators
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+
+	for idx := 0; idx < len(expr); {
+		ch := expr[idx]
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			idx++
+		case isDigit(ch):
+			end := idx
+			for end < len(expr) && (isWordChar(expr[end]) || expr[end] == '#' || expr[end] == '@') {
+				end++
+			}
+
+			value, err := parseNumber(expr[idx:end])
+			if err != nil {
+				return nil, fmt.Errorf("%w (error token is \"%s\")", err, expr[idx:end])
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[idx:end], value: value, rest: expr[idx:]})
+			idx = end
+		case isWordChar(ch):
+			end := idx
+			for end < len(expr) && isWordChar(expr[end]) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdentifier, text: expr[idx:end], rest: expr[idx:]})
+			idx = end
+		default:
+			operator := matchOperator(expr[idx:])
+			if operator == "" {
+				return nil, fmt.Errorf("syntax error: invalid arithmetic operator (error token is \"%s\")", expr[idx:])
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, rest: expr[idx:]})
+			idx += len(operator)
+		}
+	}
+
+	return tokens, nil
+}
+
+// parseNumber parses an integer constant, either decimal, octal with a leading 0,
+// hexadecimal with a leading 0x or in an explicit base from 2 to 64 written as base#digits
+func parseNumber(text string) (int64, error) {
+	if base, digits, found := strings.Cut(text, "#"); found {
+		b, err := strconv.Atoi(base)
+		if err != nil || b < 2 || b > 64 {
+			return 0, errInvalidBase
+		}
+
+		return parseDigits(digits, int64(b))
+	}
+
+	if strings.ContainsAny(text, "@_") {
+		return 0, errInvalidNumber
+	}
+
+	switch {
+	case len(text) > 2 && (text[:2] == "0x" || text[:2] == "0X"):
+		return parseDigits(text[2:], 16)
+	case len(text) > 1 && text[0] == '0':
+		return parseDigits(text[1:], 8)
+	}
+
+	return parseDigits(text, 10)
+}
+
+// parseDigits parses digits in the given base, using 0-9, a-z, A-Z, @ and _ as digits
+// Up to base 36, lowercase and uppercase letters represent the same digits
+func parseDigits(digits string, base int64) (int64, error) {
+	if digits == "" {
+		return 0, errInvalidNumber
+	}
+
+	var value int64
+	for idx := 0; idx < len(digits); idx++ {
+		var digit int64
+
+		ch := digits[idx]
+		switch {
+		case isDigit(ch):
+			digit = int64(ch - '0')
+		case ch >= 'a' && ch <= 'z':
+			digit = int64(ch-'a') + 10
+		case ch >= 'A' && ch <= 'Z':
+			digit = int64(ch-'A') + 10
+			if base > 36 {
+				digit += 26
+			}
+		case ch == '@':
+			digit = 62
+		case ch == '_':
+			digit = 63
+		default:
+			return 0, errInvalidNumber
+		}
+
+		if digit >= base {
+			return 0, errInvalidNumber
+		}
+
+		value = value*base + digit
+	}
+
+	return value, nil
+}
+
+func matchOperator(input string) string {
+	for _, operator := range operators {
+		if strings.HasPrefix(input, operator) {
+			return operator
+		}
+	}
+
+	return ""
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isWordChar(ch byte) bool {
+	return isDigit(ch) || ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// parser builds the tree of an expression from its tokens, by recursive descent from the lowest precedence operator
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// accept consumes the next token if it is the given operator
+func (p *parser) accept(operator string) bool {
+	if t, ok := p.peek(); ok && t.kind == tokenOperator && t.text == operator {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) syntaxError() error {
+	t, ok := p.peek()
+	if !ok {
+		return errOperandExpected
+	}
+
+	return fmt.Errorf("syntax error in expression (error token is \"%s\")", t.rest)
+}
+
+// parseComma parses expressions separated by commas, whose value is the one of the last expression
+func (p *parser) parseComma() (node, error) {
+	left, err := p.parseAssignment()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(",") {
+		right, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryNode{operator: ",", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseAssignment parses a variable assignment, which is right associative, or a conditional expression
+func (p *parser) parseAssignment() (node, error) {
+	if p.pos+1 < len(p.tokens) && p.tokens[p.pos].kind == tokenIdentifier {
+		next := p.tokens[p.pos+1]
+		if _, isAssignment := assignmentOperators[next.text]; isAssignment && next.kind == tokenOperator {
+			name := p.tokens[p.pos].text
+			p.pos += 2
+
+			value, err := p.parseAssignment()
+			if err != nil {
+				return nil, err
+			}
+
+			return assignNode{name: name, operator: next.text, value: value}, nil
+		}
+	}
+
+	return p.parseConditional()
+}
+
+// parseConditional parses the ternary operator cond ? then : otherwise
+func (p *parser) parseConditional() (node, error) {
+	condition, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.accept("?") {
+		return condition, nil
+	}
+
+	then, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.accept(":") {
+		return nil, p.syntaxError()
+	}
+
+	otherwise, err := p.parseAssignment()
+	if err != nil {
+		return nil, err
+	}
+
+	return ternaryNode{condition: condition, then: then, otherwise: otherwise}, nil
+}
+
+// parseBinary parses binary operators binding at least as tight as the given precedence
+// All of them are left associative except for **
+func (p *parser) parseBinary(minPrecedence int) (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokenOperator {
+			return left, nil
+		}
+
+		precedence, isBinary := binaryPrecedence[t.text]
+		if !isBinary || precedence < minPrecedence {
+			return left, nil
+		}
+		p.pos++
+
+		nextPrecedence := precedence + 1
+		if t.text == "**" {
+			nextPrecedence = precedence
+		}
+
+		right, err := p.parseBinary(nextPrecedence)
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryNode{operator: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, errOperandExpected
+	}
+
+	if t.kind == tokenOperator {
+		switch t.text {
+		case "+", "-", "!", "~":
+			p.pos++
+
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+
+			return unaryNode{operator: t.text, operand: operand}, nil
+		case "++", "--":
+			p.pos++
+
+			name, isVariable := p.peek()
+			if !isVariable || name.kind != tokenIdentifier {
+				return nil, p.syntaxError()
+			}
+			p.pos++
+
+			return incrementNode{name: name.text, delta: incrementDelta(t.text), prefix: true}, nil
+		}
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	t, _ := p.peek()
+
+	operand, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.kind != tokenIdentifier {
+		return operand, nil
+	}
+
+	for _, operator := range []string{"++", "--"} {
+		if p.accept(operator) {
+			return incrementNode{name: t.text, delta: incrementDelta(operator)}, nil
+		}
+	}
+
+	return operand, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, errOperandExpected
+	}
+
+	switch t.kind {
+	case tokenNumber:
+		p.pos++
+		return numberNode{value: t.value}, nil
+	case tokenIdentifier:
+		p.pos++
+		return variableNode{name: t.text}, nil
+	}
+
+	if !p.accept("(") {
+		return nil, p.syntaxError()
+	}
+
+	inner, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.accept(")") {
+		return nil, fmt.Errorf("missing `)' (error token is \"%s\")", p.remaining())
+	}
+
+	return inner, nil
+}
+
+// remaining returns the input left from the current token
+func (p *parser) remaining() string {
+	if t, ok := p.peek(); ok {
+		return t.rest
+	}
+	return ""
+}
+
+func incrementDelta(operator string) int64 {
+	if operator == "++" {
+		return 1
+	}
+	return -1
+}
@@ -3,27 +3,36 @@ package autocompleter
 import (
 	"slices"
 
+	"github.com/SebastianRichiteanu/Gosh/internal/commands"
 	"github.com/SebastianRichiteanu/Gosh/internal/config"
 	"github.com/SebastianRichiteanu/Gosh/internal/logger"
 	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/variables"
 )
 
 type Autocompleter struct {
 	cfg         *config.Config
 	builtinCmds *types.CommandMap
+	functions   *types.Functions
+	commands    *commands.Table
+	vars        *variables.Store
 	logger      *logger.Logger
 }
 
-func NewAutocompleter(builtinCmds *types.CommandMap, cfg *config.Config, logger *logger.Logger) *Autocompleter {
+func NewAutocompleter(builtinCmds *types.CommandMap, functions *types.Functions, commandTable *commands.Table, vars *variables.Store,
+	cfg *config.Config, logger *logger.Logger) *Autocompleter {
 	return &Autocompleter{
 		cfg:         cfg,
 		builtinCmds: builtinCmds,
+		functions:   functions,
+		commands:    commandTable,
+		vars:        vars,
 		logger:      logger,
 	}
 }
 
 // Autocomplete generates a list of possible completions for a given prefix
-// It combines suggestions from known built-in commands and executable files in the system's PATH
+// It combines suggestions from shell functions, known built-in commands and executable files in the system's PATH
 func (a *Autocompleter) Autocomplete(builtinCmds types.CommandMap, input string) []string {
 	if !a.cfg.EnableAutoComplete {
 		return nil
@@ -39,6 +48,7 @@ func (a *Autocompleter) Autocomplete(builtinCmds types.CommandMap, input string)
 	}
 
 	var suffixes []string
+	suffixes = append(suffixes, a.autoCompleteFunctions(input)...)
 	suffixes = append(suffixes, a.autoCompletebuiltinCmds(input)...)
 	suffixes = append(suffixes, a.autoCompleteExecutables(input)...)
 
@@ -19,3 +19,21 @@ func (a *Autocompleter) autoCompletebuiltinCmds(prefix string) []string {
 
 	return builtinCmdsSuffixes
 }
+
+// autoCompleteFunctions finds completions for shell functions based on the given prefix
+func (a *Autocompleter) autoCompleteFunctions(prefix string) []string {
+	if a.functions == nil {
+		return nil
+	}
+
+	var functionsSuffixes []string
+
+	for name := range *a.functions {
+		after, found := strings.CutPrefix(name, prefix)
+		if found {
+			functionsSuffixes = append(functionsSuffixes, after)
+		}
+	}
+
+	return functionsSuffixes
+}
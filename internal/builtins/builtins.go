@@ -1,35 +1,110 @@
 package builtins
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
-	"time"
+	"syscall"
 
+	"github.com/SebastianRichiteanu/Gosh/internal/commands"
+	"github.com/SebastianRichiteanu/Gosh/internal/jobs"
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
 	"github.com/SebastianRichiteanu/Gosh/internal/types"
 	"github.com/SebastianRichiteanu/Gosh/internal/utils"
 )
 
 const (
-	BuiltinExit    = "exit"
-	BuiltinEcho    = "echo"
-	BuiltinPwd     = "pwd"
-	BuiltinCd      = "cd"
-	BuiltinType    = "type"
-	BuiltinClear   = "clear"
-	BuiltinSource  = "source"
-	BuiltinExport  = "export"
-	BuiltinHistory = "history"
-	BuiltinAlias   = "alias"
-	BuiltinUnalias = "unalias"
+	BuiltinExit     = "exit"
+	BuiltinEcho     = "echo"
+	BuiltinPwd      = "pwd"
+	BuiltinCd       = "cd"
+	BuiltinType     = "type"
+	BuiltinClear    = "clear"
+	BuiltinSource   = "source"
+	BuiltinDot      = "."
+	BuiltinExport   = "export"
+	BuiltinHistory  = "history"
+	BuiltinAlias    = "alias"
+	BuiltinUnalias  = "unalias"
+	BuiltinJobs     = "jobs"
+	BuiltinFg       = "fg"
+	BuiltinBg       = "bg"
+	BuiltinWait     = "wait"
+	BuiltinDisown   = "disown"
+	BuiltinSet      = "set"
+	BuiltinLet      = "let"
+	BuiltinShopt    = "shopt"
+	BuiltinUnset    = "unset"
+	BuiltinReadonly = "readonly"
+	BuiltinDeclare  = "declare"
+	BuiltinLocal    = "local"
+	BuiltinBreak    = "break"
+	BuiltinContinue = "continue"
+	BuiltinReturn   = "return"
+	BuiltinShift    = "shift"
+	BuiltinTrap     = "trap"
+	BuiltinTest     = "test"
+	BuiltinBracket  = "["
+	BuiltinRead     = "read"
+	BuiltinExec     = "exec"
+	BuiltinHash     = "hash"
 
 	ClearControlSeq = "\033[H\033[2J"
 )
 
+// runFunc runs a builtin, reporting its failure as an error
+type runFunc func(ctx context.Context, args []string, stdio types.Stdio, shell types.Shell) error
+
+// builtin implements types.Builtin with the function running it
+// The error returned by the function is printed to stderr and gives the exit status 1, unless it is a *types.StatusError
+type builtin struct {
+	usage types.Usage
+	run   runFunc
+}
+
+func (b builtin) Usage() types.Usage {
+	return b.usage
+}
+
+func (b builtin) Run(ctx context.Context, args []string, stdio types.Stdio, shell types.Shell) int {
+	return reportError(b.run(ctx, args, stdio, shell), stdio.Stderr)
+}
+
+// reportError prints the error returned by a builtin, if there is one, and returns the matching exit status
+func reportError(err error, stderr io.Writer) int {
+	if err == nil {
+		return 0
+	}
+
+	// Writing to a pipe whose reader is gone (e.g. history | head) ends the builtin silently, like SIGPIPE ends a process
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe) {
+		return 128 + int(syscall.SIGPIPE)
+	}
+
+	// A builtin stopped by Ctrl+C (e.g. wait) ends silently as well, like SIGINT ends a process
+	if errors.Is(err, context.Canceled) {
+		return 128 + int(syscall.SIGINT)
+	}
+
+	var statusErr *types.StatusError
+	if !errors.As(err, &statusErr) {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if statusErr.Err != nil {
+		fmt.Fprintln(stderr, err)
+	}
+	return statusErr.Status
+}
+
 // InitBuiltinCmds initializes all built-in commands and stores them in a CommandMap for easy lookup
-func InitBuiltinCmds(exitChannel chan int, reloadCfgChannel chan bool, historyFile *string, aliases *types.Aliases, aliasFile *string) types.CommandMap {
+func InitBuiltinCmds(exitChannel chan int, reloadCfgChannel chan bool, historyFile *string, aliases *types.Aliases, aliasFile *string,
+	jobTable *jobs.Table, options *types.Options, functions *types.Functions, trapTable *traps.Table, commandTable *commands.Table) types.CommandMap {
 	builtinCmds := make(types.CommandMap)
 
 	builtinCmds[BuiltinExit] = builtinExit(exitChannel)
@@ -38,218 +113,374 @@ func InitBuiltinCmds(exitChannel chan int, reloadCfgChannel chan bool, historyFi
 	builtinCmds[BuiltinCd] = builtinCd()
 
 	builtinCmds[BuiltinClear] = builtinClear()
-	builtinCmds[BuiltinSource] = builtinSource(reloadCfgChannel)
-	builtinCmds[BuiltinExport] = builtinExport(reloadCfgChannel)
+	builtinCmds[BuiltinSource] = builtinSource(BuiltinSource)
+	builtinCmds[BuiltinDot] = builtinSource(BuiltinDot)
 	builtinCmds[BuiltinHistory] = builtinHistory(historyFile)
 
 	builtinCmds[BuiltinAlias] = builtinAlias(aliases, aliasFile)
 	builtinCmds[BuiltinUnalias] = builtinUnalias(aliases, aliasFile)
 
-	builtinCmds[BuiltinType] = builtinType(builtinCmds)
+	builtinCmds[BuiltinJobs] = builtinJobs(jobTable)
+	builtinCmds[BuiltinFg] = builtinFg(jobTable)
+	builtinCmds[BuiltinBg] = builtinBg(jobTable)
+	builtinCmds[BuiltinWait] = builtinWait(jobTable)
+	builtinCmds[BuiltinDisown] = builtinDisown(jobTable)
+
+	builtinCmds[BuiltinSet] = builtinSet(options)
+	builtinCmds[BuiltinShopt] = builtinShopt(options)
+
+	addVariableCmds(builtinCmds, reloadCfgChannel)
+
+	builtinCmds[BuiltinBreak] = builtinLoopControl(BuiltinBreak)
+	builtinCmds[BuiltinContinue] = builtinLoopControl(BuiltinContinue)
+	builtinCmds[BuiltinReturn] = builtinReturn()
+	builtinCmds[BuiltinShift] = builtinShift()
+	builtinCmds[BuiltinRead] = builtinRead()
+	builtinCmds[BuiltinExec] = builtinExec()
+	builtinCmds[BuiltinTrap] = builtinTrap(trapTable)
+	builtinCmds[BuiltinHash] = builtinHash(commandTable)
+
+	builtinCmds[BuiltinType] = builtinType(builtinCmds, functions, commandTable)
 
 	return builtinCmds
 }
 
 // builtinExit defines the exit behavior of the shell
-// It terminates the program with an optional exit code
-func builtinExit(exitChannel chan int) types.Command {
-	return func(code ...string) (string, error) {
-		if len(code) == 0 {
-			exitChannel <- 0
-			return "", nil
-		}
-
-		codeAsInt, err := strconv.Atoi(code[0])
-		if err != nil {
-			return "", err
-		}
+// It terminates the program with the given exit code, or with the exit status of the last command
+func builtinExit(exitChannel chan int) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "exit [n]", MaxArgs: 1},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			code := shell.LastStatus()
+			if len(args) > 0 {
+				var err error
+				if code, err = strconv.Atoi(args[0]); err != nil {
+					return &types.StatusError{Status: 2, Err: fmt.Errorf("%s: %s: numeric argument required", BuiltinExit, args[0])}
+				}
+			}
 
-		exitChannel <- codeAsInt
-		return "", nil
+			exitChannel <- code
+			select {} // The closer ends the process, the commands following exit must not run in the meantime
+		},
 	}
 }
 
 // builtinEcho defines the echo behavior of the shell
 // It prints the provided arguments to stdout
-func builtinEcho() types.Command {
-	return func(args ...string) (string, error) {
-		return strings.Join(args, " ") + "\n", nil
+func builtinEcho() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "echo [arg ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, _ types.Shell) error {
+			_, err := io.WriteString(stdio.Stdout, strings.Join(args, " ")+"\n")
+			return err
+		},
 	}
 }
 
 // builtinPwd defines the pwd behavior of the shell
 // It prints the current working directory
-func builtinPwd() types.Command {
-	return func() (string, error) {
-		currentDir, err := os.Getwd()
-		if err != nil {
-			return "", err
-		}
-		return currentDir + "\n", nil
+func builtinPwd() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "pwd"},
+		run: func(_ context.Context, _ []string, stdio types.Stdio, shell types.Shell) error {
+			currentDir, err := shell.Dir()
+			if err != nil {
+				return err
+			}
+
+			_, err = io.WriteString(stdio.Stdout, currentDir+"\n")
+			return err
+		},
 	}
 }
 
 // builtinCd defines the cd behavior of the shell
-// It changes the current working directory to the given path
-func builtinCd() types.Command {
-	return func(dir string) (string, error) {
-		expandedPath, err := utils.ExpandHomePath(dir)
-		if err != nil {
-			return "", err
-		}
+// It changes the current working directory to the given path, or to the home directory without one
+func builtinCd() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "cd [dir]", MaxArgs: 1},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			dir := "~"
+			if len(args) > 0 {
+				dir = args[0]
+			}
 
-		if err := os.Chdir(expandedPath); err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				dir = strings.TrimPrefix(dir, "/")
-				return "", fmt.Errorf("%s: /%s: No such file or directory", BuiltinCd, dir)
+			expandedPath, err := utils.ExpandHomePath(dir)
+			if err != nil {
+				return err
 			}
-			return "", err
-		}
 
-		return "", nil
+			if err := shell.Chdir(expandedPath); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					dir = strings.TrimPrefix(dir, "/")
+					return fmt.Errorf("%s: /%s: No such file or directory", BuiltinCd, dir)
+				}
+				return err
+			}
+
+			return nil
+		},
 	}
 }
 
 // builtinType defines the type behavior of the shell
-// It prints the type of a given command (either a built-in or external command).
-func builtinType(builtinCmds types.CommandMap) types.Command {
-	return func(cmd string) (string, error) {
-		if _, isKnownCmd := builtinCmds[cmd]; isKnownCmd || cmd == BuiltinType {
-			return "", fmt.Errorf("%s is a shell builtin", cmd)
-		}
-
-		fullPath := utils.FindPath(cmd)
-		if fullPath == "" {
-			return "", fmt.Errorf("%s: not found", cmd)
-		}
+// It prints the type of the given commands (either a function, a built-in or external command).
+// External commands are looked up in the command table, which tells the ones already remembered
+func builtinType(builtinCmds types.CommandMap, functions *types.Functions, commandTable *commands.Table) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "type name [name ...]", MinArgs: 1, MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, shell types.Shell) error {
+			var errs []error
+
+			for _, cmd := range args {
+				if fn, isFunction := (*functions)[cmd]; isFunction {
+					fmt.Fprintf(stdio.Stdout, "%s is a function\n%s\n", cmd, fn.Text)
+					continue
+				}
+
+				if _, isKnownCmd := builtinCmds[cmd]; isKnownCmd || cmd == BuiltinType {
+					fmt.Fprintf(stdio.Stdout, "%s is a shell builtin\n", cmd)
+					continue
+				}
+
+				fullPath, isHashed := commandTable.Find(cmd, searchPath(shell))
+				if fullPath == "" {
+					errs = append(errs, fmt.Errorf("%s: not found", cmd))
+					continue
+				}
+
+				if isHashed {
+					fmt.Fprintf(stdio.Stdout, "%s is hashed (%s)\n", cmd, fullPath)
+				} else {
+					fmt.Fprintf(stdio.Stdout, "%s is %s\n", cmd, fullPath)
+				}
+			}
 
-		return fmt.Sprintf("%s is %s\n", cmd, fullPath), nil
+			return errors.Join(errs...)
+		},
 	}
 }
 
 // builtinClear defines the clear behavior of the shell
 // It clears the terminal screen.
-func builtinClear() types.Command {
-	return func() (string, error) {
-		return ClearControlSeq, nil
+func builtinClear() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "clear"},
+		run: func(_ context.Context, _ []string, stdio types.Stdio, _ types.Shell) error {
+			_, err := io.WriteString(stdio.Stdout, ClearControlSeq)
+			return err
+		},
+	}
+}
+
+// builtinSource defines the source behavior of the shell, also available as .
+// It runs the commands of the file in the shell itself, with the arguments following it as positional parameters
+func builtinSource(name string) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: name + " filename [arguments]", MinArgs: 1, MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			return exitStatusError(shell.Source(args[0], args[1:]))
+		},
 	}
 }
 
-// builtinSource defines the source behavior of the shell
-// It will source a file
-func builtinSource(reloadCfgChannel chan bool) types.Command {
-	return func(filePaths ...string) (string, error) {
-		for _, filePath := range filePaths {
-			if err := utils.SourceFile(filePath); err != nil {
-				return "", fmt.Errorf("could not source file at path %s: %v", filePath, err)
+// builtinLoopControl defines the break and continue behavior of the shell
+// break leaves the given number of enclosing loops, 1 by default, while continue goes on with the next iteration of the last of them
+func builtinLoopControl(name string) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: name + " [n]", MaxArgs: 1},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			if shell.LoopDepth() == 0 {
+				return &types.StatusError{Status: 0, Err: fmt.Errorf("%s: only meaningful in a `for', `while', or `until' loop", name)}
 			}
-		}
 
-		reloadCfgChannel <- true
+			levels := 1
+			if len(args) > 0 {
+				var err error
+				if levels, err = strconv.Atoi(args[0]); err != nil {
+					return fmt.Errorf("%s: %s: numeric argument required", name, args[0])
+				}
 
-		time.Sleep(time.Millisecond) // The update is a bit slow so wait a milisec before returning
+				if levels < 1 {
+					return fmt.Errorf("%s: %d: loop count out of range", name, levels)
+				}
+			}
 
-		return "", nil
+			shell.LeaveLoops(levels, name == BuiltinContinue)
+			return nil
+		},
 	}
 }
 
-func builtinExport(reloadCfgChannel chan bool) types.Command {
-	return func(line string) (string, error) {
-		utils.HandleExportLine(line)
-		reloadCfgChannel <- true
+// builtinReturn defines the return behavior of the shell
+// It leaves the function or the sourced file running, with the given exit status or the one of the last command
+func builtinReturn() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "return [n]", MaxArgs: 1},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			if !shell.CanReturn() {
+				return fmt.Errorf("%s: can only `return' from a function or sourced script", BuiltinReturn)
+			}
+			shell.Return()
 
-		time.Sleep(time.Millisecond) // The update is a bit slow so wait a milisec before returning
+			if len(args) == 0 {
+				return exitStatusError(shell.LastStatus())
+			}
 
-		return "", nil
+			status, err := strconv.Atoi(args[0])
+			if err != nil {
+				return &types.StatusError{Status: 2, Err: fmt.Errorf("%s: %s: numeric argument required", BuiltinReturn, args[0])}
+			}
+			return exitStatusError(status)
+		},
 	}
 }
 
-func builtinHistory(historyFile *string) types.Command {
-	return func(args ...string) (string, error) {
-		if historyFile == nil {
-			return "", fmt.Errorf("history file not set")
-		}
+// builtinShift defines the shift behavior of the shell
+// It drops the given number of positional parameters, 1 by default, $2 becoming $1 and so on
+// It fails when there are fewer parameters than that, leaving them as they are
+func builtinShift() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "shift [n]", MaxArgs: 1},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			count := 1
+			if len(args) > 0 {
+				var err error
+				if count, err = strconv.Atoi(args[0]); err != nil {
+					return fmt.Errorf("%s: %s: numeric argument required", BuiltinShift, args[0])
+				}
+
+				if count < 0 {
+					return fmt.Errorf("%s: %d: shift count out of range", BuiltinShift, count)
+				}
+			}
 
-		data, err := os.ReadFile(*historyFile)
-		if err != nil {
-			if os.IsNotExist(err) {
-				return "", nil // no file yet
+			positionalArgs := shell.PositionalArgs()
+			if count > len(positionalArgs) {
+				return exitStatusError(1)
 			}
-			return "", err
-		}
 
-		var sb strings.Builder
+			shell.SetPositionalArgs(positionalArgs[count:])
+			return nil
+		},
+	}
+}
+
+// builtinRead defines the read behavior of the shell
+// It reads a line from stdin, or from the terminal the shell reads from, and assigns its fields to the given variables
+func builtinRead() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "read [-rs] [-a array] [-d delim] [-n nchars] [-p prompt] [-t timeout] [name ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, shell types.Shell) error {
+			return exitStatusError(shell.Read(args, stdio))
+		},
+	}
+}
 
-		lines := strings.Split(string(data), "\n")
-		for idx, line := range lines {
-			line = strings.TrimSpace(line)
-			if line != "" {
-				sb.WriteString(fmt.Sprintf("%d  %s\n", idx+1, line))
+// builtinExec defines the exec behavior of the shell
+// It replaces the shell with the given command. Without one, exec only keeps its redirections, which the shell applies for good
+func builtinExec() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "exec [command [argument ...]]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			if len(args) == 0 {
+				return nil
 			}
-		}
 
-		return sb.String(), nil
+			return exitStatusError(shell.Exec(args))
+		},
 	}
 }
 
-func builtinAlias(aliases *types.Aliases, aliasFile *string) types.Command {
-	return func(args ...string) (string, error) {
-		if aliases == nil || aliasFile == nil {
-			return "", fmt.Errorf("aliases map or alias file is nil")
-		}
-
-		if len(args) == 0 {
-			var result strings.Builder
-			for alias, command := range *aliases {
-				result.WriteString(fmt.Sprintf("alias %s='%s'\n", alias, command))
+func builtinHistory(historyFile *string) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "history", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, _ []string, stdio types.Stdio, _ types.Shell) error {
+			if historyFile == nil {
+				return fmt.Errorf("history file not set")
 			}
-			return result.String(), nil
-		}
 
-		for _, arg := range args {
-			parts := strings.SplitN(arg, "=", 2)
-			if len(parts) != 2 {
-				return "", fmt.Errorf("invalid alias format: %s", arg)
+			f, err := os.Open(*historyFile)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil // no file yet
+				}
+				return err
 			}
+			defer f.Close()
+
+			// The entries are written as they are read, so a reader such as head gets them right away and can stop early
+			idx := 0
+			return utils.ScanHistoryEntries(f, func(entry string) error {
+				idx++
+				_, err := fmt.Fprintf(stdio.Stdout, "%d  %s\n", idx, entry)
+				return err
+			})
+		},
+	}
+}
 
-			alias := parts[0]
-			command := strings.TrimSpace(parts[1])
+func builtinAlias(aliases *types.Aliases, aliasFile *string) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "alias [name=value ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, _ types.Shell) error {
+			if aliases == nil || aliasFile == nil {
+				return fmt.Errorf("aliases map or alias file is nil")
+			}
 
-			// validatre quotes
-			if strings.Count(command, "\"")%2 != 0 || strings.Count(command, "'")%2 != 0 {
-				return "", fmt.Errorf("unterminated quotes in alias command: %s", command)
+			if len(args) == 0 {
+				for alias, command := range *aliases {
+					if _, err := fmt.Fprintf(stdio.Stdout, "alias %s='%s'\n", alias, command); err != nil {
+						return err
+					}
+				}
+				return nil
 			}
 
-			(*aliases)[alias] = command
-		}
+			for _, arg := range args {
+				parts := strings.SplitN(arg, "=", 2)
+				if len(parts) != 2 {
+					return fmt.Errorf("invalid alias format: %s", arg)
+				}
 
-		if err := saveAliases(*aliases, *aliasFile); err != nil {
-			return "", fmt.Errorf("failed to save aliases: %v", err)
-		}
+				alias := parts[0]
+				command := strings.TrimSpace(parts[1])
+
+				// validatre quotes
+				if strings.Count(command, "\"")%2 != 0 || strings.Count(command, "'")%2 != 0 {
+					return fmt.Errorf("unterminated quotes in alias command: %s", command)
+				}
+
+				(*aliases)[alias] = command
+			}
 
-		return "", nil
+			if err := saveAliases(*aliases, *aliasFile); err != nil {
+				return fmt.Errorf("failed to save aliases: %v", err)
+			}
+
+			return nil
+		},
 	}
 }
 
-func builtinUnalias(aliases *types.Aliases, aliasFile *string) types.Command {
-	return func(args ...string) (string, error) {
-		if len(args) == 0 {
-			return "", fmt.Errorf("unalias: missing arguments")
-		}
-
-		if aliases == nil || aliasFile == nil {
-			return "", fmt.Errorf("aliases map or alias file is nil")
-		}
+func builtinUnalias(aliases *types.Aliases, aliasFile *string) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "unalias name [name ...]", MinArgs: 1, MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, _ types.Stdio, _ types.Shell) error {
+			if aliases == nil || aliasFile == nil {
+				return fmt.Errorf("aliases map or alias file is nil")
+			}
 
-		for _, alias := range args {
-			delete(*aliases, alias)
-		}
+			for _, alias := range args {
+				delete(*aliases, alias)
+			}
 
-		if err := saveAliases(*aliases, *aliasFile); err != nil {
-			return "", fmt.Errorf("failed to save aliases: %v", err)
-		}
+			if err := saveAliases(*aliases, *aliasFile); err != nil {
+				return fmt.Errorf("failed to save aliases: %v", err)
+			}
 
-		return "", nil
+			return nil
+		},
 	}
 }
 
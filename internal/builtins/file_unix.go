@@ -0,0 +1,40 @@
+//go:build unix
+
+package builtins
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// isAccessible evaluates -r, -w and -x, checking whether the shell may read, write or execute a file
+func isAccessible(path, operator string) bool {
+	mode := uint32(unix.R_OK)
+	switch operator {
+	case "-w":
+		mode = unix.W_OK
+	case "-x":
+		mode = unix.X_OK
+	}
+
+	return unix.Access(path, mode) == nil
+}
+
+// testFileStatus evaluates -O and -G, checking whether the shell owns a file, and -N, whether it was modified since it was last read
+func testFileStatus(operator string, info os.FileInfo) bool {
+	stat, isUnix := info.Sys().(*syscall.Stat_t)
+	if !isUnix {
+		return false
+	}
+
+	switch operator {
+	case "-O":
+		return int(stat.Uid) == os.Geteuid()
+	case "-G":
+		return int(stat.Gid) == os.Getegid()
+	}
+
+	return modifiedSinceRead(stat)
+}
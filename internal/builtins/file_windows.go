@@ -0,0 +1,46 @@
+package builtins
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isAccessible evaluates -r, -w and -x from the attributes of a file, as Windows has no access check on behalf of the shell
+// Every file can be read, the read-only ones cannot be written and only the directories and the programs can be executed
+func isAccessible(path, operator string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	switch operator {
+	case "-w":
+		return info.Mode().Perm()&0200 != 0
+	case "-x":
+		return info.IsDir() || isProgram(path)
+	}
+
+	return true
+}
+
+// isProgram checks whether a file has one of the extensions of the programs, listed by PATHEXT
+func isProgram(path string) bool {
+	extensions := os.Getenv("PATHEXT")
+	if extensions == "" {
+		extensions = ".com;.exe;.bat;.cmd"
+	}
+
+	extension := filepath.Ext(path)
+	for _, candidate := range filepath.SplitList(extensions) {
+		if extension != "" && strings.EqualFold(extension, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// testFileStatus evaluates -O, -G and -N, which are always false, as Windows gives neither the owner of a file nor the time it was last read
+func testFileStatus(string, os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,102 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/commands"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// builtinHash defines the hash behavior of the shell
+// hash name... remembers the full paths of the commands, hash -p path name... pins a path for them and hash -r forgets them all
+// Without names, it lists the remembered commands with the number of times they ran
+func builtinHash(commandTable *commands.Table) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "hash [-r] [-p pathname] [name ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, shell types.Shell) error {
+			reset := false
+			pinnedPath := ""
+
+		options:
+			for len(args) > 0 {
+				switch args[0] {
+				case "-r":
+					reset = true
+				case "-p":
+					if len(args) < 2 {
+						return &types.StatusError{Status: 2, Err: fmt.Errorf("%s: -p: option requires an argument", BuiltinHash)}
+					}
+					pinnedPath = args[1]
+					args = args[1:]
+				case "--":
+					args = args[1:]
+					break options
+				default:
+					if len(args[0]) > 1 && args[0][0] == '-' {
+						return &types.StatusError{Status: 2, Err: fmt.Errorf("%s: %s: invalid option", BuiltinHash, args[0])}
+					}
+					break options
+				}
+				args = args[1:]
+			}
+
+			if reset {
+				commandTable.Reset()
+			}
+
+			if len(args) == 0 {
+				if pinnedPath != "" {
+					return &types.StatusError{Status: 2, Err: fmt.Errorf("%s: usage: hash [-r] [-p pathname] [name ...]", BuiltinHash)}
+				}
+				if reset {
+					return nil
+				}
+				return listCommands(stdio.Stdout, commandTable, searchPath(shell))
+			}
+
+			var errs []error
+			for _, name := range args {
+				if pinnedPath != "" {
+					commandTable.Pin(name, pinnedPath)
+					continue
+				}
+
+				if commandTable.Remember(name, searchPath(shell)) == "" {
+					errs = append(errs, fmt.Errorf("%s: %s: not found", BuiltinHash, name))
+				}
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// listCommands lists the commands remembered in the table for the given PATH along with the number of times they ran
+func listCommands(w io.Writer, commandTable *commands.Table, path string) error {
+	entries := commandTable.Entries(path)
+	if len(entries) == 0 {
+		_, err := fmt.Fprintf(w, "%s: hash table empty\n", BuiltinHash)
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, "hits\tcommand"); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(w, "%4d\t%s\n", entry.Hits, entry.Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// searchPath returns the PATH of the shell, which commands are searched in whether it is exported or not
+func searchPath(shell types.Shell) string {
+	path, _ := shell.Variables().Lookup(types.PathEnvVar)
+	return path
+}
@@ -0,0 +1,215 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/jobs"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// builtinJobs defines the jobs behavior of the shell
+// It lists the jobs of the shell, with their process group IDs for -l or only the process group IDs for -p
+func builtinJobs(jobTable *jobs.Table) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "jobs [-lp]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, _ types.Shell) error {
+			withPids := false
+			onlyPids := false
+
+			for _, arg := range args {
+				switch arg {
+				case "-l":
+					withPids = true
+				case "-p":
+					onlyPids = true
+				default:
+					return fmt.Errorf("%s: %s: invalid option", BuiltinJobs, arg)
+				}
+			}
+
+			var err error
+			for _, job := range jobTable.Jobs() {
+				if onlyPids {
+					_, err = fmt.Fprintf(stdio.Stdout, "%d\n", job.Pgid)
+				} else {
+					_, err = io.WriteString(stdio.Stdout, jobTable.Format(job, withPids)+"\n")
+				}
+
+				if err != nil {
+					break
+				}
+			}
+
+			// The listed jobs count as notified, the finished ones are removed
+			jobTable.Notify(io.Discard)
+
+			return err
+		},
+	}
+}
+
+// builtinFg defines the fg behavior of the shell
+// It resumes a job in the foreground and waits for it, the exit status being the one of the job
+func builtinFg(jobTable *jobs.Table) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "fg [job_spec]", MaxArgs: 1},
+		run: func(ctx context.Context, args []string, stdio types.Stdio, shell types.Shell) error {
+			job, err := findJob(jobTable, BuiltinFg, args)
+			if err != nil {
+				return err
+			}
+
+			status, err := jobTable.Foreground(ctx, job, stdio.Stdout)
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %v", BuiltinFg, err)
+			}
+
+			// Killing the job with Ctrl+C stops the line, as for a job started in the foreground
+			if jobTable.Interrupted(job) {
+				shell.Interrupt()
+			}
+
+			return exitStatusError(status)
+		},
+	}
+}
+
+// builtinBg defines the bg behavior of the shell
+// It resumes stopped jobs in the background
+func builtinBg(jobTable *jobs.Table) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "bg [job_spec ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, _ types.Shell) error {
+			if len(args) == 0 {
+				args = []string{""}
+			}
+
+			for _, spec := range args {
+				job, err := findJob(jobTable, BuiltinBg, []string{spec})
+				if err != nil {
+					return err
+				}
+
+				line, err := jobTable.Background(job)
+				if err != nil {
+					return fmt.Errorf("%s: %v", BuiltinBg, err)
+				}
+
+				io.WriteString(stdio.Stdout, line)
+			}
+
+			return nil
+		},
+	}
+}
+
+// builtinWait defines the wait behavior of the shell
+// It waits for the given jobs (or pids) to finish, or for all of them without arguments,
+// the exit status being the one of the last job waited for. Ctrl+C stops waiting
+func builtinWait(jobTable *jobs.Table) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "wait [id ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(ctx context.Context, args []string, _ types.Stdio, _ types.Shell) error {
+			if len(args) == 0 {
+				return jobTable.WaitAll(ctx)
+			}
+
+			status := 0
+			for _, arg := range args {
+				var job *jobs.Job
+				var err error
+
+				if pid, convErr := strconv.Atoi(arg); convErr == nil {
+					job, err = jobTable.FindByPid(pid)
+					if err != nil {
+						return &types.StatusError{
+							Status: 127,
+							Err:    fmt.Errorf("%s: pid %d is not a child of this shell", BuiltinWait, pid),
+						}
+					}
+				} else if job, err = findJob(jobTable, BuiltinWait, []string{arg}); err != nil {
+					return &types.StatusError{Status: 127, Err: err}
+				}
+
+				status, err = jobTable.Wait(ctx, job)
+				if errors.Is(err, context.Canceled) {
+					return err
+				}
+				if err != nil {
+					return &types.StatusError{Status: status, Err: fmt.Errorf("%s: %s: %v", BuiltinWait, arg, err)}
+				}
+			}
+
+			return exitStatusError(status)
+		},
+	}
+}
+
+// builtinDisown defines the disown behavior of the shell
+// It removes jobs from the job table, or all of them for -a, so they are no longer reported
+func builtinDisown(jobTable *jobs.Table) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "disown [-a] [job_spec ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, _ types.Stdio, _ types.Shell) error {
+			if len(args) == 1 && args[0] == "-a" {
+				for _, job := range jobTable.Jobs() {
+					jobTable.Disown(job)
+				}
+				return nil
+			}
+
+			if len(args) == 0 {
+				args = []string{""}
+			}
+
+			for _, spec := range args {
+				job, err := findJob(jobTable, BuiltinDisown, []string{spec})
+				if err != nil {
+					return err
+				}
+
+				jobTable.Disown(job)
+			}
+
+			return nil
+		},
+	}
+}
+
+// findJob returns the job referred to by the only argument of a builtin, or the current job without arguments
+func findJob(jobTable *jobs.Table, builtin string, args []string) (*jobs.Job, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("%s: too many arguments", builtin)
+	}
+
+	spec := ""
+	if len(args) == 1 {
+		spec = args[0]
+	}
+
+	job, err := jobTable.Find(spec)
+	if err != nil {
+		if spec == "" {
+			spec = "current"
+		}
+		return nil, fmt.Errorf("%s: %s: %v", builtin, spec, err)
+	}
+
+	return job, nil
+}
+
+// exitStatusError returns the error reporting a non zero exit status of a builtin, without any message
+func exitStatusError(status int) error {
+	if status == 0 {
+		return nil
+	}
+
+	return &types.StatusError{Status: status}
+}
@@ -0,0 +1,31 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/arithmetic"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// builtinLet defines the let behavior of the shell
+// It evaluates every argument as an arithmetic expression, succeeding if the last one is not 0
+func builtinLet() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "let arg [arg ...]", MinArgs: 1, MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, exprs []string, _ types.Stdio, shell types.Shell) error {
+			var value int64
+			for _, expr := range exprs {
+				var err error
+				if value, err = arithmetic.Evaluate(expr, shell.Variables()); err != nil {
+					return fmt.Errorf("%s: %w", BuiltinLet, err)
+				}
+			}
+
+			if value == 0 {
+				return exitStatusError(1)
+			}
+			return nil
+		},
+	}
+}
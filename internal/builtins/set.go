@@ -0,0 +1,153 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// setOptionNames are the names of the options handled by set -o, in the order they are listed
+var setOptionNames = []string{"errexit", "noclobber", "noglob", "nounset", "pipefail", "xtrace"}
+
+// setOptionFlags maps the single letter flags of set to the names of their options
+var setOptionFlags = map[rune]string{
+	'e': "errexit",
+	'u': "nounset",
+	'x': "xtrace",
+	'C': "noclobber",
+	'f': "noglob",
+}
+
+// builtinSet defines the set behavior of the shell
+// It turns shell options on with -e, -u, -x, -C, -f or -o name and off with + instead of -
+// -o alone lists the options and +o alone lists them as the set commands restoring them
+// The arguments following the options (e.g. set -- a b) replace the positional parameters, see splitSetArgs
+func builtinSet(options *types.Options) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "set [-eufxC] [-o option] [--] [arg ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, shell types.Shell) error {
+			if len(args) == 1 && (args[0] == "-o" || args[0] == "+o") {
+				listSetOptions(stdio.Stdout, options, args[0] == "+o")
+				return nil
+			}
+
+			args, positionalArgs, hasPositionalArgs := splitSetArgs(args)
+			if err := setOptions(options, args, stdio.Stdout); err != nil {
+				return err
+			}
+
+			if hasPositionalArgs {
+				shell.SetPositionalArgs(positionalArgs)
+			}
+			return nil
+		},
+	}
+}
+
+// setOptions turns on or off the options given to set, listing them after a -o or +o which is not followed by a name
+func setOptions(options *types.Options, args []string, stdout io.Writer) error {
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			return fmt.Errorf("%s: %s: invalid option", BuiltinSet, arg)
+		}
+		enable := arg[0] == '-'
+
+		for _, flag := range arg[1:] {
+			if flag != 'o' {
+				name, isKnown := setOptionFlags[flag]
+				if !isKnown {
+					return fmt.Errorf("%s: %c%c: invalid option", BuiltinSet, arg[0], flag)
+				}
+
+				*setOption(options, name) = enable
+				continue
+			}
+
+			idx++
+			if idx >= len(args) {
+				listSetOptions(stdout, options, !enable)
+				return nil
+			}
+
+			option := setOption(options, strings.ToLower(args[idx]))
+			if option == nil {
+				return fmt.Errorf("%s: %s: invalid option name", BuiltinSet, args[idx])
+			}
+			*option = enable
+		}
+	}
+
+	return nil
+}
+
+// splitSetArgs splits the arguments of set into its options and the positional parameters following them,
+// which start at the first argument that is not an option, or right after -- or -
+// It also returns whether there are positional parameters to set, as set -- alone removes all of them
+func splitSetArgs(args []string) ([]string, []string, bool) {
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+
+		switch {
+		case arg == "--" || arg == "-":
+			return args[:idx], args[idx+1:], true
+		case len(arg) > 1 && (arg[0] == '-' || arg[0] == '+'):
+			// The name of -o name is not a positional parameter
+			if strings.HasSuffix(arg, "o") && idx+1 < len(args) {
+				idx++
+			}
+		default:
+			return args[:idx], args[idx:], true
+		}
+	}
+
+	return args, nil, false
+}
+
+// listSetOptions lists the options of set with their state, or as the set commands restoring them
+func listSetOptions(w io.Writer, options *types.Options, asCommands bool) {
+	for _, name := range setOptionNames {
+		enabled := *setOption(options, name)
+
+		if asCommands {
+			flag := "+o"
+			if enabled {
+				flag = "-o"
+			}
+			fmt.Fprintf(w, "%s %s %s\n", BuiltinSet, flag, name)
+		} else {
+			fmt.Fprintf(w, "%-15s%s\n", name, optionState(enabled))
+		}
+	}
+}
+
+// setOption returns the option matching a set -o name, or nil if there is none
+func setOption(options *types.Options, name string) *bool {
+	switch name {
+	case "errexit":
+		return &options.Errexit
+	case "noclobber":
+		return &options.Noclobber
+	case "noglob":
+		return &options.Noglob
+	case "nounset":
+		return &options.Nounset
+	case "pipefail":
+		return &options.Pipefail
+	case "xtrace":
+		return &options.Xtrace
+	}
+
+	return nil
+}
+
+func optionState(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,107 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// shoptOptionNames are the names of the options handled by shopt, in the order they are listed
+var shoptOptionNames = []string{"dotglob", "failglob", "nullglob"}
+
+// builtinShopt defines the shopt behavior of the shell
+// It turns shell options on with -s and off with -u, and lists them otherwise, -p listing them as shopt commands
+// and -q only reporting through the exit status whether they are all on
+func builtinShopt(options *types.Options) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "shopt [-pqsu] [optname ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, _ types.Shell) error {
+			var enable, disable, printCommands, quiet bool
+
+			for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+				for _, flag := range args[0][1:] {
+					switch flag {
+					case 's':
+						enable = true
+					case 'u':
+						disable = true
+					case 'p':
+						printCommands = true
+					case 'q':
+						quiet = true
+					default:
+						return fmt.Errorf("%s: -%c: invalid option", BuiltinShopt, flag)
+					}
+				}
+				args = args[1:]
+			}
+
+			if enable && disable {
+				return fmt.Errorf("%s: cannot set and unset shell options simultaneously", BuiltinShopt)
+			}
+
+			for _, name := range args {
+				if shoptOption(options, name) == nil {
+					return fmt.Errorf("%s: %s: invalid shell option name", BuiltinShopt, name)
+				}
+			}
+
+			if (enable || disable) && len(args) > 0 {
+				for _, name := range args {
+					*shoptOption(options, name) = enable
+				}
+				return nil
+			}
+
+			names := args
+			if len(names) == 0 {
+				names = shoptOptionNames
+			}
+
+			allEnabled := true
+
+			for _, name := range names {
+				enabled := *shoptOption(options, name)
+				allEnabled = allEnabled && enabled
+
+				// shopt -s or shopt -u without names only lists the options in that state
+				if (enable && !enabled) || (disable && enabled) || quiet {
+					continue
+				}
+
+				if printCommands {
+					flag := "-u"
+					if enabled {
+						flag = "-s"
+					}
+					fmt.Fprintf(stdio.Stdout, "%s %s %s\n", BuiltinShopt, flag, name)
+				} else {
+					fmt.Fprintf(stdio.Stdout, "%-15s\t%s\n", name, optionState(enabled))
+				}
+			}
+
+			// Asking for specific options fails if any of them is off
+			if len(args) > 0 && !allEnabled {
+				return exitStatusError(1)
+			}
+
+			return nil
+		},
+	}
+}
+
+// shoptOption returns the option matching a shopt name, or nil if there is none
+func shoptOption(options *types.Options, name string) *bool {
+	switch name {
+	case "dotglob":
+		return &options.Dotglob
+	case "failglob":
+		return &options.Failglob
+	case "nullglob":
+		return &options.Nullglob
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build linux || dragonfly || openbsd
+
+package builtins
+
+import "syscall"
+
+// modifiedSinceRead checks whether a file was modified since it was last read
+func modifiedSinceRead(stat *syscall.Stat_t) bool {
+	return stat.Mtim.Nano() > stat.Atim.Nano()
+}
@@ -0,0 +1,361 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/mattn/go-isatty"
+)
+
+// unaryTests are the operators testing a single operand, a file or a string
+var unaryTests = []string{
+	"-a", "-b", "-c", "-d", "-e", "-f", "-g", "-h", "-k", "-p", "-r", "-s", "-t", "-u", "-w", "-x",
+	"-G", "-L", "-N", "-O", "-S", "-n", "-z", "-v",
+}
+
+// binaryTests are the operators comparing two operands, as strings, integers or files
+var binaryTests = []string{
+	"=", "==", "!=", "<", ">",
+	"-eq", "-ne", "-lt", "-le", "-gt", "-ge",
+	"-nt", "-ot", "-ef",
+}
+
+// IntegerTests are the binary operators comparing integers
+var IntegerTests = []string{"-eq", "-ne", "-lt", "-le", "-gt", "-ge"}
+
+// builtinTest defines the test and [ behavior of the shell
+// It evaluates the conditional expression made of its arguments, succeeding when it is true and failing with 2 on an error
+// [ takes a closing ] as its last argument
+func builtinTest(name string) types.Builtin {
+	synopsis := name + " [expr]"
+	if name == BuiltinBracket {
+		synopsis = name + " [expr] ]"
+	}
+
+	return builtin{
+		usage: types.Usage{Synopsis: synopsis, MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			if name == BuiltinBracket {
+				if len(args) == 0 || args[len(args)-1] != "]" {
+					return &types.StatusError{Status: 2, Err: fmt.Errorf("%s: missing `]'", name)}
+				}
+				args = args[:len(args)-1]
+			}
+
+			tp := testParser{args: args, shell: shell}
+
+			result, err := tp.evaluate()
+			if err != nil {
+				return &types.StatusError{Status: 2, Err: fmt.Errorf("%s: %w", name, err)}
+			}
+
+			if !result {
+				return exitStatusError(1)
+			}
+			return nil
+		},
+	}
+}
+
+// testParser evaluates the arguments of test, following the rules of POSIX for up to 4 arguments,
+// then the precedence of the operators: !, then -a, then -o, with parentheses for grouping
+type testParser struct {
+	args  []string
+	pos   int
+	shell types.Shell
+}
+
+// evaluate evaluates the whole expression, which must use up all the arguments
+func (tp *testParser) evaluate() (bool, error) {
+	result, err := tp.evaluateCount(len(tp.args))
+	if err != nil {
+		return false, err
+	}
+
+	if tp.pos < len(tp.args) {
+		return false, fmt.Errorf("%s: unexpected argument", tp.args[tp.pos])
+	}
+	return result, nil
+}
+
+// evaluateCount evaluates the expression made of the next count arguments, whose meaning depends on their number
+func (tp *testParser) evaluateCount(count int) (bool, error) {
+	args := tp.args[tp.pos:]
+
+	switch count {
+	case 0:
+		return false, nil
+	case 1:
+		tp.pos++
+		return args[0] != "", nil
+	case 2:
+		switch {
+		case args[0] == "!":
+			tp.pos++
+			result, err := tp.evaluateCount(1)
+			return !result, err
+		case IsUnaryTest(args[0]):
+			tp.pos += 2
+			return UnaryTest(args[0], args[1], tp.shell), nil
+		}
+		return false, fmt.Errorf("%s: unary operator expected", args[0])
+	case 3:
+		switch {
+		case IsBinaryTest(args[1]):
+			tp.pos += 3
+			return BinaryTest(args[1], args[0], args[2], tp.shell)
+		case args[1] == "-a" || args[1] == "-o":
+			return tp.parseOr()
+		case args[0] == "!":
+			tp.pos++
+			result, err := tp.evaluateCount(2)
+			return !result, err
+		case args[0] == "(" && args[2] == ")":
+			tp.pos++
+			result, err := tp.evaluateCount(1)
+			tp.pos++
+			return result, err
+		}
+		return false, fmt.Errorf("%s: binary operator expected", args[1])
+	case 4:
+		switch {
+		case args[0] == "!":
+			tp.pos++
+			result, err := tp.evaluateCount(3)
+			return !result, err
+		case args[0] == "(" && args[3] == ")":
+			tp.pos++
+			result, err := tp.evaluateCount(2)
+			tp.pos++
+			return result, err
+		}
+	}
+
+	return tp.parseOr()
+}
+
+// parseOr evaluates expressions joined by -o
+func (tp *testParser) parseOr() (bool, error) {
+	result, err := tp.parseAnd()
+	for err == nil && tp.accept("-o") {
+		var right bool
+		right, err = tp.parseAnd()
+		result = result || right
+	}
+
+	return result, err
+}
+
+// parseAnd evaluates expressions joined by -a
+func (tp *testParser) parseAnd() (bool, error) {
+	result, err := tp.parseNot()
+	for err == nil && tp.accept("-a") {
+		var right bool
+		right, err = tp.parseNot()
+		result = result && right
+	}
+
+	return result, err
+}
+
+// parseNot evaluates an expression negated by any number of !
+func (tp *testParser) parseNot() (bool, error) {
+	if tp.accept("!") {
+		result, err := tp.parseNot()
+		return !result, err
+	}
+
+	return tp.parsePrimary()
+}
+
+// parsePrimary evaluates a parenthesized expression, a unary or binary test, or a single string
+func (tp *testParser) parsePrimary() (bool, error) {
+	args := tp.args[tp.pos:]
+
+	switch {
+	case len(args) == 0:
+		return false, errors.New("argument expected")
+	case len(args) >= 3 && IsBinaryTest(args[1]):
+		tp.pos += 3
+		return BinaryTest(args[1], args[0], args[2], tp.shell)
+	case args[0] == "(":
+		tp.pos++
+		result, err := tp.parseOr()
+		if err != nil {
+			return false, err
+		}
+
+		if !tp.accept(")") {
+			return false, errors.New("`)' expected")
+		}
+		return result, nil
+	case len(args) >= 2 && IsUnaryTest(args[0]):
+		tp.pos += 2
+		return UnaryTest(args[0], args[1], tp.shell), nil
+	}
+
+	tp.pos++
+	return args[0] != "", nil
+}
+
+// accept consumes the next argument if it is the given operator
+func (tp *testParser) accept(operator string) bool {
+	if tp.pos < len(tp.args) && tp.args[tp.pos] == operator {
+		tp.pos++
+		return true
+	}
+	return false
+}
+
+// IsUnaryTest checks whether an argument is an operator testing a single operand, such as -f or -z
+func IsUnaryTest(operator string) bool {
+	return slices.Contains(unaryTests, operator)
+}
+
+// IsBinaryTest checks whether an argument is an operator comparing two operands, such as = or -lt
+func IsBinaryTest(operator string) bool {
+	return slices.Contains(binaryTests, operator)
+}
+
+// UnaryTest evaluates a unary operator: -n and -z test the length of a string, -v whether a variable is set,
+// -t whether a file descriptor is a terminal, the other ones test a file, relative to the working directory of the shell
+func UnaryTest(operator, operand string, shell types.Shell) bool {
+	switch operator {
+	case "-n":
+		return operand != ""
+	case "-z":
+		return operand == ""
+	case "-v":
+		_, isSet := shell.Variables().Lookup(operand)
+		return isSet
+	case "-t":
+		fd, err := strconv.Atoi(operand)
+		if err != nil {
+			return false
+		}
+		return isatty.IsTerminal(uintptr(fd))
+	}
+
+	path := shell.ResolvePath(operand)
+
+	switch operator {
+	case "-h", "-L":
+		info, err := os.Lstat(path)
+		return err == nil && info.Mode()&os.ModeSymlink != 0
+	case "-r", "-w", "-x":
+		return isAccessible(path, operator)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	mode := info.Mode()
+	switch operator {
+	case "-b":
+		return mode&os.ModeDevice != 0 && mode&os.ModeCharDevice == 0
+	case "-c":
+		return mode&os.ModeCharDevice != 0
+	case "-d":
+		return mode.IsDir()
+	case "-f":
+		return mode.IsRegular()
+	case "-g":
+		return mode&os.ModeSetgid != 0
+	case "-k":
+		return mode&os.ModeSticky != 0
+	case "-p":
+		return mode&os.ModeNamedPipe != 0
+	case "-s":
+		return info.Size() > 0
+	case "-u":
+		return mode&os.ModeSetuid != 0
+	case "-S":
+		return mode&os.ModeSocket != 0
+	}
+
+	switch operator {
+	case "-O", "-G", "-N":
+		return testFileStatus(operator, info)
+	}
+
+	return true // -a and -e, the file exists
+}
+
+// BinaryTest evaluates a binary operator: =, ==, !=, < and > compare strings, -eq, -ne, -lt, -le, -gt and -ge integers,
+// -nt and -ot the modification times of files and -ef whether two paths are the same file
+func BinaryTest(operator, left, right string, shell types.Shell) (bool, error) {
+	switch operator {
+	case "=", "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "-nt", "-ot":
+		leftInfo, leftErr := os.Stat(shell.ResolvePath(left))
+		rightInfo, rightErr := os.Stat(shell.ResolvePath(right))
+		if operator == "-ot" {
+			leftInfo, leftErr, rightInfo, rightErr = rightInfo, rightErr, leftInfo, leftErr
+		}
+
+		// A file is newer than a file that does not exist
+		if leftErr != nil {
+			return false, nil
+		}
+		return rightErr != nil || leftInfo.ModTime().After(rightInfo.ModTime()), nil
+	case "-ef":
+		leftInfo, leftErr := os.Stat(shell.ResolvePath(left))
+		rightInfo, rightErr := os.Stat(shell.ResolvePath(right))
+		return leftErr == nil && rightErr == nil && os.SameFile(leftInfo, rightInfo), nil
+	}
+
+	leftInt, err := parseTestInteger(left)
+	if err != nil {
+		return false, err
+	}
+
+	rightInt, err := parseTestInteger(right)
+	if err != nil {
+		return false, err
+	}
+
+	return CompareIntegers(operator, leftInt, rightInt), nil
+}
+
+// CompareIntegers evaluates one of the IntegerTests on two integers
+func CompareIntegers(operator string, left, right int64) bool {
+	switch operator {
+	case "-eq":
+		return left == right
+	case "-ne":
+		return left != right
+	case "-lt":
+		return left < right
+	case "-le":
+		return left <= right
+	case "-gt":
+		return left > right
+	}
+
+	return left >= right
+}
+
+// parseTestInteger parses an operand of an integer comparison, which may be surrounded by blanks
+func parseTestInteger(operand string) (int64, error) {
+	value, err := strconv.ParseInt(strings.TrimSpace(operand), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: integer expression expected", operand)
+	}
+
+	return value, nil
+}
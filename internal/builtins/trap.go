@@ -0,0 +1,86 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// builtinTrap defines the trap behavior of the shell
+// trap 'command' SIGNAL... runs the command whenever one of the signals is received, an empty command ignoring them,
+// and trap - SIGNAL... restores their default behavior. The signals include the EXIT, ERR, DEBUG and RETURN pseudo-signals
+// Without arguments or with -p, it lists the traps as the trap commands setting them again
+func builtinTrap(trapTable *traps.Table) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "trap [-p] [[command] signal_spec ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, _ types.Shell) error {
+			printTraps := false
+
+			if len(args) > 0 {
+				switch args[0] {
+				case "-p":
+					printTraps = true
+					args = args[1:]
+				case "--":
+					args = args[1:]
+				}
+			}
+
+			if printTraps || len(args) == 0 {
+				return listTraps(stdio.Stdout, trapTable, args)
+			}
+
+			// A single argument is a signal to reset
+			command, signals := args[0], args[1:]
+			if len(args) == 1 {
+				command, signals = "-", args
+			}
+
+			var errs []error
+			for _, spec := range signals {
+				name, err := traps.ParseSignal(spec)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", BuiltinTrap, err))
+					continue
+				}
+
+				if command == "-" {
+					trapTable.Reset(name)
+				} else {
+					trapTable.Set(name, command)
+				}
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// listTraps lists the traps of the given signals, or all of them, as the trap commands setting them again
+func listTraps(w io.Writer, trapTable *traps.Table, specs []string) error {
+	names := trapTable.Names()
+
+	if len(specs) > 0 {
+		names = nil
+		for _, spec := range specs {
+			name, err := traps.ParseSignal(spec)
+			if err != nil {
+				return fmt.Errorf("%s: %w", BuiltinTrap, err)
+			}
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		if command, isTrapped := trapTable.Get(name); isTrapped {
+			fmt.Fprintf(w, "%s -- '%s' %s\n", BuiltinTrap, strings.ReplaceAll(command, "'", `'\''`), name)
+		}
+	}
+
+	return nil
+}
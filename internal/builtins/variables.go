@@ -0,0 +1,330 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/variables"
+)
+
+// addVariableCmds adds the builtins working on the variables of the shell they run in
+func addVariableCmds(builtinCmds types.CommandMap, reloadCfgChannel chan bool) {
+	builtinCmds[BuiltinLet] = builtinLet()
+	builtinCmds[BuiltinExport] = builtinExport(reloadCfgChannel)
+	builtinCmds[BuiltinUnset] = builtinUnset()
+	builtinCmds[BuiltinReadonly] = builtinReadonly()
+	builtinCmds[BuiltinDeclare] = builtinDeclare()
+	builtinCmds[BuiltinLocal] = builtinLocal()
+	builtinCmds[BuiltinTest] = builtinTest(BuiltinTest)
+	builtinCmds[BuiltinBracket] = builtinTest(BuiltinBracket)
+}
+
+// builtinExport defines the export behavior of the shell
+// It marks variables as exported, assigning them first when given as NAME=value, -n removing the mark instead
+// Without names or with -p, it lists the exported variables
+// The exports of a subshell only apply to its own variables, they do not reload the config of the shell
+func builtinExport(reloadCfgChannel chan bool) types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "export [-np] [name[=value] ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, shell types.Shell) error {
+			vars := shell.Variables()
+
+			flags, names, err := parseFlags(BuiltinExport, args, "np")
+			if err != nil {
+				return err
+			}
+
+			if len(names) == 0 {
+				return listVariables(stdio.Stdout, vars, func(v variables.Variable) bool { return v.Exported })
+			}
+
+			exported := !flags['n']
+
+			var errs []error
+			for _, arg := range names {
+				name, value, hasValue := strings.Cut(arg, "=")
+				if !parser.IsValidName(name) {
+					errs = append(errs, invalidIdentifier(BuiltinExport, arg))
+					continue
+				}
+
+				if hasValue {
+					if err := vars.Set(name, value); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", BuiltinExport, err))
+						continue
+					}
+				}
+
+				vars.SetAttributes(name, &exported, nil, nil)
+			}
+
+			if reloadCfgChannel != nil && !shell.IsSubshell() {
+				reloadCfgChannel <- true
+
+				time.Sleep(time.Millisecond) // The update is a bit slow so wait a milisec before returning
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// builtinUnset defines the unset behavior of the shell
+// It removes variables, except for the readonly ones
+func builtinUnset() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "unset [-v] [name ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			vars := shell.Variables()
+
+			_, names, err := parseFlags(BuiltinUnset, args, "v")
+			if err != nil {
+				return err
+			}
+
+			var errs []error
+			for _, name := range names {
+				if !parser.IsValidName(name) {
+					errs = append(errs, invalidIdentifier(BuiltinUnset, name))
+					continue
+				}
+
+				if err := vars.Unset(name); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", BuiltinUnset, err))
+				}
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// builtinReadonly defines the readonly behavior of the shell
+// It marks variables as readonly, assigning them first when given as NAME=value
+// Without names or with -p, it lists the readonly variables
+func builtinReadonly() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "readonly [-p] [name[=value] ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, shell types.Shell) error {
+			vars := shell.Variables()
+
+			_, names, err := parseFlags(BuiltinReadonly, args, "p")
+			if err != nil {
+				return err
+			}
+
+			if len(names) == 0 {
+				return listVariables(stdio.Stdout, vars, func(v variables.Variable) bool { return v.Readonly })
+			}
+
+			readonly := true
+
+			var errs []error
+			for _, arg := range names {
+				name, value, hasValue := strings.Cut(arg, "=")
+				if !parser.IsValidName(name) {
+					errs = append(errs, invalidIdentifier(BuiltinReadonly, arg))
+					continue
+				}
+
+				if hasValue {
+					if err := vars.Set(name, value); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", BuiltinReadonly, err))
+						continue
+					}
+				}
+
+				vars.SetAttributes(name, nil, &readonly, nil)
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// builtinDeclare defines the declare behavior of the shell
+// It sets the attributes of variables, -x exporting them, -r making them readonly and -i making them integers,
+// + instead of - removing the attribute, and assigns them when given as NAME=value. Inside a function, the variables are local
+// With -p or without names, it lists the variables as the declare commands recreating them
+func builtinDeclare() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "declare [-irxp] [name[=value] ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, stdio types.Stdio, shell types.Shell) error {
+			vars := shell.Variables()
+
+			var exported, readonly, integer *bool
+			printDeclarations := false
+
+			for len(args) > 0 && len(args[0]) > 1 && (args[0][0] == '-' || args[0][0] == '+') {
+				if args[0] == "--" {
+					args = args[1:]
+					break
+				}
+
+				enable := args[0][0] == '-'
+				for _, flag := range args[0][1:] {
+					switch flag {
+					case 'x':
+						exported = &enable
+					case 'r':
+						if !enable {
+							return fmt.Errorf("%s: +r: invalid option", BuiltinDeclare)
+						}
+						readonly = &enable
+					case 'i':
+						integer = &enable
+					case 'p':
+						printDeclarations = true
+					default:
+						return fmt.Errorf("%s: %c%c: invalid option", BuiltinDeclare, args[0][0], flag)
+					}
+				}
+				args = args[1:]
+			}
+
+			if len(args) == 0 {
+				return listVariables(stdio.Stdout, vars, func(v variables.Variable) bool {
+					return (exported == nil || v.Exported == *exported) &&
+						(readonly == nil || v.Readonly) &&
+						(integer == nil || v.Integer == *integer)
+				})
+			}
+
+			if printDeclarations {
+				var errs []error
+
+				for _, name := range args {
+					v, found := vars.Get(name)
+					if !found {
+						errs = append(errs, fmt.Errorf("%s: %s: not found", BuiltinDeclare, name))
+						continue
+					}
+
+					if _, err := io.WriteString(stdio.Stdout, variables.Declaration(name, v)+"\n"); err != nil {
+						return err
+					}
+				}
+
+				return errors.Join(errs...)
+			}
+
+			var errs []error
+			for _, arg := range args {
+				name, value, hasValue := strings.Cut(arg, "=")
+				if !parser.IsValidName(name) {
+					errs = append(errs, invalidIdentifier(BuiltinDeclare, arg))
+					continue
+				}
+
+				if err := vars.Local(name); err != nil && !errors.Is(err, variables.ErrNotInScope) {
+					errs = append(errs, fmt.Errorf("%s: %w", BuiltinDeclare, err))
+					continue
+				}
+
+				if err := declareVariable(vars, name, value, hasValue, exported, readonly, integer); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", BuiltinDeclare, err))
+				}
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// builtinLocal defines the local behavior of the shell
+// It declares variables local to the function it runs in, assigning them when given as NAME=value
+func builtinLocal() types.Builtin {
+	return builtin{
+		usage: types.Usage{Synopsis: "local [name[=value] ...]", MaxArgs: types.UnlimitedArgs},
+		run: func(_ context.Context, args []string, _ types.Stdio, shell types.Shell) error {
+			vars := shell.Variables()
+
+			var errs []error
+
+			for _, arg := range args {
+				name, value, hasValue := strings.Cut(arg, "=")
+				if !parser.IsValidName(name) {
+					errs = append(errs, invalidIdentifier(BuiltinLocal, arg))
+					continue
+				}
+
+				if err := vars.Local(name); err != nil {
+					return fmt.Errorf("%s: %w", BuiltinLocal, err)
+				}
+
+				if hasValue {
+					if err := vars.Set(name, value); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", BuiltinLocal, err))
+					}
+				}
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// declareVariable changes the attributes of a variable and assigns it
+// The integer attribute is set before the assignment so the value gets evaluated, readonly after it so the assignment succeeds
+func declareVariable(vars *variables.Store, name, value string, hasValue bool, exported, readonly, integer *bool) error {
+	if err := vars.SetAttributes(name, exported, nil, integer); err != nil {
+		return err
+	}
+
+	if hasValue {
+		if err := vars.Set(name, value); err != nil {
+			return err
+		}
+	}
+
+	if readonly != nil {
+		return vars.SetAttributes(name, nil, readonly, nil)
+	}
+
+	return nil
+}
+
+// listVariables writes the variables matching a filter as the declare commands recreating them, one at a time
+func listVariables(w io.Writer, vars *variables.Store, filter func(variables.Variable) bool) error {
+	for _, name := range vars.Names() {
+		if v, found := vars.Get(name); found && filter(v) {
+			if _, err := io.WriteString(w, variables.Declaration(name, v)+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseFlags parses the leading flags of a builtin, such as -n or -np, which must be among the allowed ones
+// It returns the flags found and the remaining arguments
+func parseFlags(builtin string, args []string, allowed string) (map[rune]bool, []string, error) {
+	flags := make(map[rune]bool)
+
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		if args[0] == "--" {
+			return flags, args[1:], nil
+		}
+
+		for _, flag := range args[0][1:] {
+			if !strings.ContainsRune(allowed, flag) {
+				return nil, nil, fmt.Errorf("%s: -%c: invalid option", builtin, flag)
+			}
+			flags[flag] = true
+		}
+		args = args[1:]
+	}
+
+	return flags, args, nil
+}
+
+func invalidIdentifier(builtin, arg string) error {
+	return fmt.Errorf("%s: `%s': not a valid identifier", builtin, arg)
+}
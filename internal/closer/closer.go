@@ -8,29 +8,50 @@ import (
 	"syscall"
 
 	"github.com/SebastianRichiteanu/Gosh/internal/config"
+	"github.com/SebastianRichiteanu/Gosh/internal/jobs"
 	"github.com/SebastianRichiteanu/Gosh/internal/logger"
 	"github.com/SebastianRichiteanu/Gosh/internal/prompt"
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
 )
 
+// TrapRunner runs the commands trapped for signals, such as the executor of the shell
+// It is also interrupted by the Ctrl+C received while no foreground job runs
+type TrapRunner interface {
+	RunTrap(signal string)
+	RunPendingTraps()
+	Interrupt()
+}
+
 type Closer struct {
 	exitChannel   chan int
 	osSignalsChan chan os.Signal
 
-	prompt *prompt.Prompt
-	cfg    *config.Config
-	logger *logger.Logger
+	prompt     *prompt.Prompt // nil for a non-interactive shell
+	cfg        *config.Config
+	logger     *logger.Logger
+	jobs       *jobs.Table
+	traps      *traps.Table
+	trapRunner TrapRunner
 }
 
 // NewCloser creates and returns a new Closer instance
-func NewCloser(exitChannel chan int, prompt *prompt.Prompt, cfg *config.Config, logger *logger.Logger) *Closer {
-	return &Closer{
+// It starts catching the signals right away, so that none is missed before ListenForSignals runs
+func NewCloser(exitChannel chan int, prompt *prompt.Prompt, cfg *config.Config, logger *logger.Logger,
+	jobTable *jobs.Table, trapTable *traps.Table, trapRunner TrapRunner) *Closer {
+	c := &Closer{
 		exitChannel:   exitChannel,
 		osSignalsChan: make(chan os.Signal, 1),
 
-		prompt: prompt,
-		cfg:    cfg,
-		logger: logger,
+		prompt:     prompt,
+		cfg:        cfg,
+		logger:     logger,
+		jobs:       jobTable,
+		traps:      trapTable,
+		trapRunner: trapRunner,
 	}
+	signal.Notify(c.osSignalsChan, traps.Catchable...)
+
+	return c
 }
 
 // Recover catches panics, logs the error and stack trace, and gracefully exits the program
@@ -43,13 +64,9 @@ func (c *Closer) Recover() {
 }
 
 // ListenForSignals continuously listens for OS signals and internal exit codes to initiate a graceful shutdown
+// The signals trapped by the user run their commands instead, once the executor gets to them
+// The exit codes are sent by the exit builtin and by the main loop once the shell is done
 func (c *Closer) ListenForSignals() {
-	signal.Notify(c.osSignalsChan,
-		syscall.SIGTERM,
-		syscall.SIGHUP,
-		syscall.SIGQUIT,
-	)
-
 	for {
 		select {
 		case sig := <-c.osSignalsChan:
@@ -60,20 +77,71 @@ func (c *Closer) ListenForSignals() {
 	}
 }
 
-// HandleSignal maps an incoming OS signal to an appropriate exit code and triggers a shutdown
+// HandleSignal dispatches an incoming OS signal to the command trapped for it, if there is one
+// Otherwise, it maps the signal to an appropriate exit code and triggers a shutdown
 func (c *Closer) HandleSignal(sig os.Signal) {
+	if command, isTrapped := c.traps.Get(traps.SignalName(sig)); isTrapped {
+		if command != "" {
+			c.traps.Raise(traps.SignalName(sig))
+		}
+		return
+	}
+
+	// An interactive shell does not end on Ctrl+C (which discards the line at the prompt) nor on SIGQUIT,
+	// they are meant for the foreground job, which gets the ones sent to the shell itself
+	// Without one, the shell was running builtins (e.g. a while loop), Ctrl+C stops the line instead
+	if (sig == syscall.SIGINT || sig == syscall.SIGQUIT) && c.prompt != nil {
+		if !c.jobs.SignalForeground(sig.(syscall.Signal)) && sig == syscall.SIGINT && c.trapRunner != nil {
+			c.trapRunner.Interrupt()
+		}
+		return
+	}
+
 	// By convention exit codes related to signals are often: code = 128 + signal number
 	c.HandleExit(128 + int(sig.(syscall.Signal)))
 }
 
 // HandleExit performs cleanup of all components and terminates the program with the specified exit code
+// The command trapped on EXIT runs first, it may change the exit code by calling exit
 func (c *Closer) HandleExit(code int) {
+	code = c.runExitTrap(code)
+
 	close(c.osSignalsChan)
 	close(c.exitChannel)
 
-	c.prompt.Close()
+	c.Cleanup()
+
+	os.Exit(code)
+}
+
+// Cleanup closes all the components, before the program exits or exec replaces it with another program
+func (c *Closer) Cleanup() {
+	if c.prompt != nil {
+		c.prompt.Close()
+	}
 	c.cfg.Close()
 	c.logger.Close()
+}
 
-	os.Exit(code)
+// runExitTrap runs the command trapped on EXIT, returning the exit code, which is the one given to exit if the command calls it
+// The signals received right before exiting still get their commands run first
+// exit blocks once it sent its code, which is received here since the closer is busy exiting
+func (c *Closer) runExitTrap(code int) int {
+	if c.trapRunner == nil {
+		return code
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.trapRunner.RunPendingTraps()
+		c.trapRunner.RunTrap(traps.Exit)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return code
+	case exitCode := <-c.exitChannel:
+		return exitCode
+	}
 }
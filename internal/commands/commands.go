@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/utils"
+)
+
+// Table caches the full paths of the commands found in the directories of PATH, keyed by name, like the hash table of other shells
+// PATH is the value of the shell variable, exported or not, which the callers pass along
+// The table is emptied when PATH changes, and a command whose cached path disappeared is searched for again
+// It also indexes the executables of each directory for the autocompletion, a directory being read again once it changed
+type Table struct {
+	mu          sync.Mutex
+	path        string // the PATH the commands were found in
+	entries     map[string]*Entry
+	directories map[string]*directory
+}
+
+// Entry is a command remembered in the table
+type Entry struct {
+	Name   string
+	Path   string
+	Hits   int  // the number of times the command was looked up to run it
+	Pinned bool // the path was set with hash -p, it is kept when PATH changes and never checked
+}
+
+// directory holds the names of the executables of a directory of PATH, as of its last modification
+type directory struct {
+	modTime time.Time
+	names   []string
+}
+
+func NewTable() *Table {
+	return &Table{
+		entries:     make(map[string]*Entry),
+		directories: make(map[string]*directory),
+	}
+}
+
+// Lookup returns the full path of the command to run for the given name, searched in the given PATH, or an empty string if there is none,
+// counting a hit
+// Names with a slash are paths already, they are not remembered
+func (t *Table) Lookup(name, path string) string {
+	fullPath, _ := t.find(name, path, true, true)
+	return fullPath
+}
+
+// Remember looks up the command for the given name like Lookup does, without counting a hit
+func (t *Table) Remember(name, path string) string {
+	fullPath, _ := t.find(name, path, true, false)
+	return fullPath
+}
+
+// Find returns the full path of the command for the given name, without remembering it
+// It tells whether the command was already remembered, e.g. for type reporting it as hashed
+func (t *Table) Find(name, path string) (string, bool) {
+	return t.find(name, path, false, false)
+}
+
+// find returns the full path of the command for the given name, and whether it was already remembered
+func (t *Table) find(name, path string, remember, hit bool) (string, bool) {
+	if name == "" || strings.ContainsRune(name, '/') {
+		return utils.FindPath(name, path), false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.checkPath(path)
+
+	if entry, isHashed := t.entries[name]; isHashed {
+		if _, err := os.Stat(entry.Path); entry.Pinned || err == nil {
+			if hit {
+				entry.Hits++
+			}
+			return entry.Path, true
+		}
+		delete(t.entries, name)
+	}
+
+	fullPath := utils.FindPath(name, path)
+	if fullPath == "" || !remember {
+		return fullPath, false
+	}
+
+	entry := &Entry{Name: name, Path: fullPath}
+	if hit {
+		entry.Hits = 1
+	}
+	t.entries[name] = entry
+
+	return fullPath, false
+}
+
+// Pin remembers the given path for the command, which is used without searching PATH
+func (t *Table) Pin(name, fullPath string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries[name] = &Entry{Name: name, Path: fullPath, Pinned: true}
+}
+
+// Reset forgets all the commands, including the pinned ones, and the executables indexed for the autocompletion
+func (t *Table) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries = make(map[string]*Entry)
+	t.directories = make(map[string]*directory)
+}
+
+// Entries returns the commands remembered in the table for the given PATH, sorted by name
+func (t *Table) Entries(path string) []Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.checkPath(path)
+
+	entries := make([]Entry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		entries = append(entries, *entry)
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return entries
+}
+
+// Executables returns the names of the commands starting with the given prefix, from the directories of the given PATH and the pinned commands
+// Only the directories modified since they were last indexed are read again
+func (t *Table) Executables(prefix, path string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.checkPath(path)
+
+	var names []string
+	for _, dir := range strings.Split(t.path, types.PathDelimiter) {
+		for _, name := range t.index(dir) {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for name, entry := range t.entries {
+		if entry.Pinned && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// index returns the names of the executables of a directory, reading it only if it changed since it was last read
+func (t *Table) index(dir string) []string {
+	info, err := os.Stat(dir)
+	if err != nil {
+		delete(t.directories, dir)
+		return nil
+	}
+
+	if indexed, isIndexed := t.directories[dir]; isIndexed && indexed.modTime.Equal(info.ModTime()) {
+		return indexed.names
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, file := range files {
+		// The type of an entry has no permission bits, they come with its info
+		fileInfo, err := file.Info()
+		if err != nil || fileInfo.IsDir() || fileInfo.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+
+	t.directories[dir] = &directory{modTime: info.ModTime(), names: names}
+	return names
+}
+
+// checkPath forgets the commands found in the directories of the previous PATH once it changed, keeping the pinned ones
+func (t *Table) checkPath(path string) {
+	if path == t.path {
+		return
+	}
+	t.path = path
+
+	for name, entry := range t.entries {
+		if !entry.Pinned {
+			delete(t.entries, name)
+		}
+	}
+}
@@ -7,23 +7,28 @@ import (
 	"strconv"
 
 	"github.com/SebastianRichiteanu/Gosh/internal/utils"
+	"github.com/SebastianRichiteanu/Gosh/internal/variables"
 )
 
 type Config struct {
 	PromptSymbol       string
+	ContinuationSymbol string
 	LogLevel           string
 	LogFile            string
 	GoshHomePath       string
 	AliasFile          string
+	GoshrcFile         string // run by the shell at startup, before reading the rest of the config
 	HistoryFile        string
 	MaxHistorySize     int
 	EnableAutoComplete bool
 	reloadCfgChannel   chan bool
+	vars               *variables.Store // the shell variables the config is read from
 }
 
-func NewConfig(reloadCfgChannel chan bool) (*Config, error) {
+func NewConfig(reloadCfgChannel chan bool, vars *variables.Store) (*Config, error) {
 	cfg := Config{
 		PromptSymbol:       defaultPromptSymbol,
+		ContinuationSymbol: defaultContinuationSymbol,
 		LogLevel:           defaultLogLevel,
 		LogFile:            defaultLogFile,
 		HistoryFile:        defaultHistoryFile,
@@ -33,6 +38,7 @@ func NewConfig(reloadCfgChannel chan bool) (*Config, error) {
 		AliasFile:          defaultAliasFile,
 
 		reloadCfgChannel: reloadCfgChannel,
+		vars:             vars,
 	}
 
 	goshrcFilePath := filepath.Join(cfg.GoshHomePath, defaultGoshrcFile)
@@ -45,9 +51,7 @@ func NewConfig(reloadCfgChannel chan bool) (*Config, error) {
 		return nil, fmt.Errorf("failed to ensure config exist: %v", err)
 	}
 
-	if err := utils.SourceFile(goshrcExpandedFilePath); err != nil {
-		return nil, err
-	}
+	cfg.GoshrcFile = goshrcExpandedFilePath
 
 	if err := cfg.Update(); err != nil {
 		return nil, fmt.Errorf("failed to update config: %v", err)
@@ -95,7 +99,7 @@ func (c *Config) listenRefreshChan() {
 }
 
 func (c *Config) Update() error {
-	if envGoshHomePath, exists := os.LookupEnv(envVarGoshHomePath); exists {
+	if envGoshHomePath, exists := c.vars.Lookup(envVarGoshHomePath); exists {
 		c.GoshHomePath = envGoshHomePath
 	}
 
@@ -108,19 +112,22 @@ func (c *Config) Update() error {
 		return fmt.Errorf("Failed to create dir: %v", err)
 	}
 
-	if prompt, exists := os.LookupEnv(envVarPromptSymbol); exists {
+	if prompt, exists := c.vars.Lookup(envVarPromptSymbol); exists {
 		c.PromptSymbol = prompt
 	}
-	if envLogLevel, exists := os.LookupEnv(envVarLogLevel); exists {
+	if continuation, exists := c.vars.Lookup(envVarContinuationSymbol); exists {
+		c.ContinuationSymbol = continuation
+	}
+	if envLogLevel, exists := c.vars.Lookup(envVarLogLevel); exists {
 		c.LogLevel = envLogLevel
 	}
-	if envLogFile, exists := os.LookupEnv(envVarLogFile); exists {
+	if envLogFile, exists := c.vars.Lookup(envVarLogFile); exists {
 		c.LogFile = envLogFile
 	}
-	if envHistoryFile, exists := os.LookupEnv(envVarHistoryFile); exists {
+	if envHistoryFile, exists := c.vars.Lookup(envVarHistoryFile); exists {
 		c.HistoryFile = envHistoryFile
 	}
-	if envMaxHistorySize, exists := os.LookupEnv(envVarMaxHistorySize); exists {
+	if envMaxHistorySize, exists := c.vars.Lookup(envVarMaxHistorySize); exists {
 		if maxHistorySize, err := strconv.Atoi(envMaxHistorySize); err == nil {
 			c.MaxHistorySize = maxHistorySize
 		} else {
@@ -128,11 +135,11 @@ func (c *Config) Update() error {
 		}
 	}
 
-	if envAliasFile, exists := os.LookupEnv(envVarAliasFile); exists {
+	if envAliasFile, exists := c.vars.Lookup(envVarAliasFile); exists {
 		c.AliasFile = envAliasFile
 	}
 
-	if envAutoComplete, exists := os.LookupEnv(envVarEnableAutoComplete); exists {
+	if envAutoComplete, exists := c.vars.Lookup(envVarEnableAutoComplete); exists {
 		c.EnableAutoComplete = envAutoComplete == "true"
 	}
 
@@ -4,6 +4,7 @@ const (
 	defaultConfig = "# Gosh config"
 
 	defaultPromptSymbol       = "$"
+	defaultContinuationSymbol = ">"
 	defaultLogLevel           = "INFO"
 	defaultEnableAutoComplete = true
 
@@ -17,6 +18,7 @@ const (
 
 const (
 	envVarPromptSymbol       = "GOSH_SHELL_SYMBOL"
+	envVarContinuationSymbol = "GOSH_CONTINUATION_SYMBOL"
 	envVarLogLevel           = "GOSH_LOG_LEVEL"
 	envVarEnableAutoComplete = "GOSH_ENABLE_AUTOCOMPLETE"
 	envVarLogFile            = "GOSH_LOG_FILE"
@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/arithmetic"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+)
+
+// expandArithmetic expands the arithmetic expansion $((...)) at the beginning of the input, writing the value of its expression
+// to the field builder. The expression is expanded first, like a double-quoted word, then evaluated
+// It returns the number of bytes consumed from the input
+func (e *Executor) expandArithmetic(input string, fb *fieldBuilder, quoted bool, ifs string) (int, error) {
+	length, _ := parser.ArithmeticLength(input[1:])
+	consumed := length + 1
+
+	value, err := e.evaluateArithmetic(input[3 : consumed-2])
+	if err != nil {
+		return 0, err
+	}
+
+	if quoted {
+		fb.write(strconv.FormatInt(value, 10))
+	} else {
+		fb.writeSplit(strconv.FormatInt(value, 10), ifs)
+	}
+
+	return consumed, nil
+}
+
+// evaluateArithmetic expands and evaluates an arithmetic expression
+func (e *Executor) evaluateArithmetic(expr string) (int64, error) {
+	expanded, err := e.expandUnsplitWord(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	return arithmetic.Evaluate(expanded, e.vars)
+}
+
+// execArithmetic runs the arithmetic command ((...)), whose expression was already expanded
+// Its exit status is 0 if the expression evaluates to a non zero value, and 1 otherwise
+func (e *Executor) execArithmetic(expr string, s streams) int {
+	value, err := arithmetic.Evaluate(expr, e.vars)
+	if err != nil {
+		fmt.Fprintf(s.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+
+	if value == 0 {
+		return statusFailure
+	}
+	return statusSuccess
+}
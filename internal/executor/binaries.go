@@ -1,59 +1,61 @@
 package executor
 
 import (
-	"errors"
 	"fmt"
-	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/SebastianRichiteanu/Gosh/internal/types"
-	"github.com/SebastianRichiteanu/Gosh/internal/utils"
 )
 
-// execBinary executes an external command by searching for the binary in the system's PATH
-// and invoking it with the provided arguments
-func (e *Executor) execBinary(prompt types.ParsedPrompt) {
-	binary := prompt.Tokens[0]
-	args := prompt.Tokens[1:]
+// prepareBinary looks up the binary in the command table, which searches the PATH of the shell, and prepares the command
+// with the provided arguments, wired to the given streams
+func (e *Executor) prepareBinary(stage types.PipelineStage, s streams) (*exec.Cmd, error) {
+	binary := stage.Tokens[0]
+	args := stage.Tokens[1:]
 
-	fullPath := utils.FindPath(binary)
-	if fullPath == "" {
-		fmt.Printf("%s: not found\n", binary)
-		return
+	// A local path is looked up from the working directory of the subshell, which the process does not share
+	name := binary
+	if strings.HasPrefix(binary, ".") {
+		name = e.ResolvePath(binary)
 	}
 
-	for idx, arg := range args {
-		if arg[0] == '~' {
-			fullArg, err := utils.ExpandHomePath(arg)
-			if err != nil {
-				continue
-			}
+	fullPath := e.commands.Lookup(name, e.searchPath())
+	if fullPath == "" {
+		return nil, fmt.Errorf("%s: %w", binary, errNotFound)
+	}
 
-			args[idx] = fullArg
-		}
+	// The command runs from its full path, which may be pinned with hash -p, under the name it was called with
+	cmd := exec.Command(fullPath, args...)
+	cmd.Args[0] = binary
+	cmd.Stdin = s.stdin
+	cmd.Stdout = s.stdout
+	cmd.Stderr = s.stderr
+	cmd.ExtraFiles = s.extraFiles
+	cmd.Dir = e.dir
+
+	// The assignments preceding the command only end up in its environment, the last value of a variable winning
+	cmd.Env = e.vars.Environ()
+	for _, a := range stage.Assignments {
+		cmd.Env = append(cmd.Env, a.Name+"="+a.Value)
 	}
 
-	cmd := exec.Command(binary, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if prompt.RedirectFile != "" {
-		outFile, err := utils.OpenFileForStdout(prompt.RedirectFile, prompt.Truncate)
-		if err != nil {
-			fmt.Println(err.Error())
-			return
-		}
-
-		switch prompt.StdStream {
-		case types.Stdout:
-			cmd.Stdout = outFile
-		case types.Stderr:
-			cmd.Stderr = outFile
-		default:
-			panic(errors.New("unable to handle unknown std stream"))
-		}
+	// exec.Cmd cannot leave the standard streams closed, the closed ones get the null device instead
+	if _, isClosed := s.stdin.(closedFd); isClosed {
+		cmd.Stdin = nil
+	}
+	if _, isClosed := s.stdout.(closedFd); isClosed {
+		cmd.Stdout = nil
 	}
+	if _, isClosed := s.stderr.(closedFd); isClosed {
+		cmd.Stderr = nil
+	}
+
+	return cmd, nil
+}
 
-	cmd.Run()
+// searchPath returns the value of the PATH variable of the shell, which is searched even once unexported (export -n PATH)
+func (e *Executor) searchPath() string {
+	path, _ := e.vars.Lookup(types.PathEnvVar)
+	return path
 }
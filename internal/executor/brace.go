@@ -0,0 +1,224 @@
+package executor
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+)
+
+// expandBraces performs brace expansion on a raw word, before any other expansion
+// {a,b,c} results in a word for each of its comma separated parts and {x..y[..step]} in a word for each number
+// or character of the sequence, the prefix and suffix around the braces being added to every word.
+// Quoted braces, ${...} and braces without a comma or a valid sequence are kept as they are
+func expandBraces(word string) []string {
+	open, close, parts := findBraceExpression(word)
+	if open == -1 {
+		return []string{word}
+	}
+
+	prefix, suffix := word[:open], word[close+1:]
+	suffixes := expandBraces(suffix)
+
+	var words []string
+	for _, part := range parts {
+		for _, expandedPart := range expandBraces(part) {
+			for _, expandedSuffix := range suffixes {
+				words = append(words, prefix+expandedPart+expandedSuffix)
+			}
+		}
+	}
+
+	return words
+}
+
+// findBraceExpression finds the first brace expression of a raw word, returning the position of its braces
+// and the words it stands for, or -1 if there is none
+func findBraceExpression(word string) (int, int, []string) {
+	inSingleQuote := false
+	inDoubleQuote := false
+
+	for i := 0; i < len(word); i++ {
+		char := word[i]
+
+		switch {
+		case char == '\\' && !inSingleQuote:
+			i++
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		case inSingleQuote:
+			continue
+		case char == '`' || strings.HasPrefix(word[i:], "$("):
+			if length, err := parser.SubstitutionLength(word[i:]); err == nil {
+				i += length - 1
+			}
+		case strings.HasPrefix(word[i:], "${"):
+			if length, err := parser.ParameterLength(word[i:], inDoubleQuote); err == nil {
+				i += length - 1
+			}
+		case char == '{' && !inDoubleQuote:
+			close, commas := matchBrace(word, i)
+			if close == -1 {
+				continue
+			}
+
+			content := word[i+1 : close]
+			if len(commas) > 0 {
+				return i, close, splitAt(word, i+1, close, commas)
+			}
+
+			if sequence, isSequence := expandSequence(content); isSequence {
+				return i, close, sequence
+			}
+		}
+	}
+
+	return -1, -1, nil
+}
+
+// matchBrace returns the position of the brace closing the one at the given position, and the positions of the commas
+// found between them outside of nested braces, or -1 if the brace is never closed
+func matchBrace(word string, open int) (int, []int) {
+	var commas []int
+
+	inSingleQuote := false
+	inDoubleQuote := false
+	depth := 0
+
+	for i := open + 1; i < len(word); i++ {
+		char := word[i]
+
+		switch {
+		case char == '\\' && !inSingleQuote:
+			i++
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		case inSingleQuote || inDoubleQuote:
+			continue
+		case char == '`' || strings.HasPrefix(word[i:], "$("):
+			if length, err := parser.SubstitutionLength(word[i:]); err == nil {
+				i += length - 1
+			}
+		case strings.HasPrefix(word[i:], "${"):
+			if length, err := parser.ParameterLength(word[i:], inDoubleQuote); err == nil {
+				i += length - 1
+			}
+		case char == '{':
+			depth++
+		case char == '}':
+			if depth == 0 {
+				return i, commas
+			}
+			depth--
+		case char == ',' && depth == 0:
+			commas = append(commas, i)
+		}
+	}
+
+	return -1, nil
+}
+
+// splitAt splits word[start:end] at the given positions
+func splitAt(word string, start, end int, positions []int) []string {
+	parts := make([]string, 0, len(positions)+1)
+	for _, position := range positions {
+		parts = append(parts, word[start:position])
+		start = position + 1
+	}
+
+	return append(parts, word[start:end])
+}
+
+// expandSequence expands the content of a sequence expression, x..y or x..y..step, where x and y are
+// either integers or single characters. Integers starting with a 0 are padded with zeros to the same width
+func expandSequence(content string) ([]string, bool) {
+	bounds := strings.Split(content, "..")
+	if len(bounds) != 2 && len(bounds) != 3 {
+		return nil, false
+	}
+
+	step := 1
+	if len(bounds) == 3 {
+		var err error
+		if step, err = strconv.Atoi(bounds[2]); err != nil {
+			return nil, false
+		}
+
+		if step < 0 {
+			step = -step
+		}
+		if step == 0 {
+			step = 1
+		}
+	}
+
+	start, startErr := strconv.Atoi(bounds[0])
+	end, endErr := strconv.Atoi(bounds[1])
+	if startErr == nil && endErr == nil {
+		width := 0
+		if isZeroPadded(bounds[0]) || isZeroPadded(bounds[1]) {
+			width = max(len(bounds[0]), len(bounds[1]))
+		}
+
+		var words []string
+		for _, n := range sequence(start, end, step) {
+			words = append(words, padNumber(n, width))
+		}
+		return words, true
+	}
+
+	if len(bounds[0]) != 1 || len(bounds[1]) != 1 || isDigit(bounds[0][0]) || isDigit(bounds[1][0]) {
+		return nil, false
+	}
+
+	var words []string
+	for _, n := range sequence(int(bounds[0][0]), int(bounds[1][0]), step) {
+		words = append(words, string(rune(n)))
+	}
+	return words, true
+}
+
+// sequence returns the numbers from start to end, going up or down by step
+func sequence(start, end, step int) []int {
+	var numbers []int
+
+	if start <= end {
+		for n := start; n <= end; n += step {
+			numbers = append(numbers, n)
+		}
+	} else {
+		for n := start; n >= end; n -= step {
+			numbers = append(numbers, n)
+		}
+	}
+
+	return numbers
+}
+
+// isZeroPadded checks whether an integer is written with leading zeros, such as 01 or -05
+func isZeroPadded(number string) bool {
+	number = strings.TrimPrefix(number, "-")
+	return len(number) > 1 && number[0] == '0'
+}
+
+// padNumber formats a number with leading zeros up to the given width, which includes the minus sign
+func padNumber(n, width int) string {
+	digits := strconv.Itoa(n)
+	if n < 0 {
+		digits = digits[1:]
+		width--
+	}
+
+	if len(digits) < width {
+		digits = strings.Repeat("0", width-len(digits)) + digits
+	}
+
+	if n < 0 {
+		return "-" + digits
+	}
+	return digits
+}
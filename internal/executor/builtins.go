@@ -2,70 +2,72 @@ package executor
 
 import (
 	"fmt"
-	"reflect"
 
 	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/variables"
 )
 
-// execBuiltin executes a built-in command and handles its output (stdout, stderr)
-func (e *Executor) execBuiltin(knownCmd types.Command, prompt types.ParsedPrompt) {
-	output := e.runBuiltin(knownCmd, prompt)
+// execBuiltin executes a built-in command wired to the given streams and returns its exit status
+// The arguments are checked against the usage the builtin declares before it runs
+func (e *Executor) execBuiltin(knownCmd types.Builtin, stage types.PipelineStage, s streams) int {
+	name, args := stage.Tokens[0], stage.Tokens[1:]
 
-	if len(output) != 2 {
-		panic(fmt.Errorf("command did not return 2 out streams"))
+	if err := knownCmd.Usage().Check(name, args); err != nil {
+		fmt.Fprintln(s.stderr, err)
+		return err.Status
 	}
 
-	stdout := output[0]
-	stderr := output[1]
-
-	if prompt.RedirectFile == "" {
-		e.handleDirectOutput(stdout, stderr)
-		return
-	}
-
-	e.handleFileOutput(prompt, stdout, stderr)
+	stdio := types.Stdio{Stdin: s.stdin, Stdout: s.stdout, Stderr: s.stderr}
+	return knownCmd.Run(e.ctx, args, stdio, e)
 }
 
-// runBuiltin runs a built-in function dynamically using reflection, passing the arguments from the prompt
-func (e *Executor) runBuiltin(cmd types.Command, prompt types.ParsedPrompt) []reflect.Value {
-	args := prompt.Tokens[1:]
+// Variables returns the variables of the shell, implementing types.Shell for the builtins
+func (e *Executor) Variables() *variables.Store {
+	return e.vars
+}
 
-	fctValue := reflect.ValueOf(cmd)
-	fctType := fctValue.Type()
+// LastStatus returns the exit status of the last command, $?
+func (e *Executor) LastStatus() int {
+	return e.lastStatus
+}
 
-	numIn := fctType.NumIn()
-	isVariadic := fctType.IsVariadic()
+// IsSubshell checks whether the executor runs a subshell, whose state is not reported back to the shell
+func (e *Executor) IsSubshell() bool {
+	return e.isSubshell
+}
 
-	if (!isVariadic && numIn != len(args)) || (isVariadic && len(args) < numIn-1) {
-		expected := fmt.Sprintf("%d", numIn)
-		if isVariadic {
-			expected = fmt.Sprintf("at least %d", numIn-1)
-		}
-		errMsg := fmt.Sprintf("error: wrong number of arguments (expected %s, got %d)", expected, len(args))
-		return []reflect.Value{
-			reflect.ValueOf(""),
-			reflect.ValueOf(errMsg),
-		}
-	}
+// PositionalArgs returns the positional parameters, $1 and the following ones
+func (e *Executor) PositionalArgs() []string {
+	return e.positionalArgs
+}
 
-	fctArgs := make([]reflect.Value, 0, numIn)
+// SetPositionalArgs replaces the positional parameters
+func (e *Executor) SetPositionalArgs(args []string) {
+	e.positionalArgs = args
+}
 
-	for i := 0; i < numIn; i++ {
-		if isVariadic && i == numIn-1 {
-			variadicSlice := reflect.MakeSlice(fctType.In(i), len(args[i:]), len(args[i:]))
-			for j, arg := range args[i:] {
-				variadicSlice.Index(j).Set(reflect.ValueOf(arg))
-			}
-			fctArgs = append(fctArgs, variadicSlice)
-			continue
-		}
+// LoopDepth returns the number of loops running, the ones of the caller of a function not counting
+func (e *Executor) LoopDepth() int {
+	return e.loopDepth
+}
 
-		fctArgs = append(fctArgs, reflect.ValueOf(args[i]))
+// LeaveLoops leaves the given number of the loops running, at most all of them, the commands following break or continue
+// being skipped. With next, the last loop left goes on with its next iteration instead
+func (e *Executor) LeaveLoops(levels int, next bool) {
+	levels = min(levels, e.loopDepth)
+	if next {
+		e.continueLoops = levels
+	} else {
+		e.breakLoops = levels
 	}
+}
 
-	if isVariadic {
-		return fctValue.CallSlice(fctArgs)
-	}
+// CanReturn checks whether a function or a sourced file runs, which return leaves
+func (e *Executor) CanReturn() bool {
+	return e.callDepth > 0
+}
 
-	return fctValue.Call(fctArgs)
+// Return leaves the function or the sourced file running, the commands following return being skipped
+func (e *Executor) Return() {
+	e.returning = true
 }
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/jobs"
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+const (
+	statusSuccess       = 0
+	statusFailure       = 1
+	statusUsage         = 2 // a conditional expression is invalid or cannot be evaluated
+	statusNotExecutable = 126
+	statusNotFound      = 127
+
+	// By convention the exit status of a command killed by a signal is 128 + signal number
+	statusSignalOffset = 128
+)
+
+var errNotFound = errors.New("not found")
+
+// executeChain runs the pipelines of a chain one after the other, each && or || operator deciding
+// whether the next pipeline runs based on the exit status of the previous one
+// It returns the exit status of the last pipeline that ran
+func (e *Executor) executeChain(chain types.Chain) int {
+	if chain.Background {
+		return e.executeBackground(chain)
+	}
+
+	status := e.executePipeline(chain.Pipelines[0], nil)
+	status, lastRan := e.continueChain(chain, status, nil)
+
+	// Only the failure of the last pipeline raises the ERR trap and ends the shell with set -e,
+	// the other ones being tested by && or ||
+	if lastRan && status != statusSuccess && e.conditionDepth == 0 {
+		// The trap sees the status of the failed pipeline in $?
+		e.lastStatus = status
+		e.RunTrap(traps.Err)
+
+		if e.options != nil && e.options.Errexit {
+			e.exited = true
+		}
+	}
+
+	return status
+}
+
+// continueChain runs the pipelines following the first one of a chain, given the exit status of the first one
+// It also returns whether the last pipeline of the chain ran
+func (e *Executor) continueChain(chain types.Chain, status int, job *jobs.Job) (int, bool) {
+	lastRan := len(chain.Operators) == 0
+
+	for idx, operator := range chain.Operators {
+		if e.stopped() {
+			break
+		}
+
+		if (operator == types.OperatorAnd && status != statusSuccess) ||
+			(operator == types.OperatorOr && status == statusSuccess) {
+			continue
+		}
+
+		status = e.executePipeline(chain.Pipelines[idx+1], job)
+		lastRan = idx == len(chain.Operators)-1
+	}
+
+	return status, lastRan
+}
+
+// executeBackground launches a chain as a background job in its own process group and returns right away
+// A chain of several pipelines is driven by a goroutine, whose result is the exit status of the job
+// The chain runs in a subshell, so that it neither changes the shell (e.g. cd / &) nor shares its state with the commands following it
+func (e *Executor) executeBackground(chain types.Chain) int {
+	job := e.jobs.NewJob(chain.Text, true)
+	subshell := e.subshell(e.stdio)
+
+	var driver *jobs.Process
+	if len(chain.Pipelines) > 1 {
+		driver = e.jobs.StartTask(job, true)
+	}
+
+	processes, err := subshell.startPipeline(chain.Pipelines[0], job, driver == nil)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		if driver != nil {
+			e.jobs.FinishTask(driver, statusFailure)
+		}
+		return statusFailure
+	}
+
+	if driver != nil {
+		go func() {
+			status, _ := subshell.continueChain(chain, e.jobs.WaitProcesses(processes), job)
+			e.jobs.FinishTask(driver, status)
+		}()
+	}
+
+	id := e.jobs.Add(job)
+
+	// Only an interactive shell reports the jobs it starts, like other shells, its subshells staying silent
+	report := e.interactive && !e.isSubshell
+
+	pids := e.jobs.Pids(job)
+	if len(pids) == 0 {
+		if report {
+			fmt.Fprintf(e.stdio.stderr, "[%d]\n", id)
+		}
+		return statusSuccess
+	}
+
+	e.lastBackgroundPid = pids[len(pids)-1]
+	if report {
+		fmt.Fprintf(e.stdio.stderr, "[%d] %d\n", id, pids[0])
+	}
+
+	return statusSuccess
+}
+
+// exitStatus maps the error returned when waiting on a command to its exit status
+func exitStatus(err error) int {
+	if err == nil {
+		return statusSuccess
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return statusFailure
+	}
+
+	if waitStatus, ok := exitErr.Sys().(syscall.WaitStatus); ok && waitStatus.Signaled() {
+		return statusSignalOffset + int(waitStatus.Signal())
+	}
+
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,310 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/glob"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// runsInShell checks whether a stage is a command running in the shell itself rather than as a builtin or a binary:
+// a compound command, a function definition, a function call, or source given a file
+func (e *Executor) runsInShell(stage types.PipelineStage) bool {
+	if stage.Compound != nil || stage.Function != nil {
+		return true
+	}
+
+	if _, isFunction := e.lookupFunction(stage); isFunction {
+		return true
+	}
+
+	if isExec(stage) {
+		return true
+	}
+
+	return len(stage.Tokens) > 1 && (stage.Tokens[0] == builtins.BuiltinSource || stage.Tokens[0] == builtins.BuiltinDot)
+}
+
+// executeInShell runs a stage which runs in the shell itself, after expanding it and applying its redirections
+// to the streams of the shell for the time it runs
+// Without redirections, the streams are left alone, so that the ones exec sets in a function or a group stay set
+func (e *Executor) executeInShell(stage types.PipelineStage) int {
+	expandedStage, err := e.expandStage(stage)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+
+	e.trace(expandedStage)
+
+	// The redirections of exec without a command change the streams of the shell for good, rather than for the time it runs
+	if isExec(expandedStage) && len(expandedStage.Tokens) == 1 {
+		return e.executeExec(expandedStage)
+	}
+
+	if len(expandedStage.Redirections) == 0 {
+		return e.runInShell(expandedStage)
+	}
+
+	s, files, err := e.applyRedirections(expandedStage.Redirections, e.stdio)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+	defer closeAll(files)
+
+	stdio := e.stdio
+	e.stdio = s
+	defer func() { e.stdio = stdio }()
+
+	return e.runInShell(expandedStage)
+}
+
+// runInShell runs an expanded stage which runs in the shell itself, with the streams of the shell
+func (e *Executor) runInShell(stage types.PipelineStage) int {
+	if stage.Compound != nil {
+		return e.executeCompound(stage.Compound)
+	}
+
+	if stage.Function != nil {
+		return e.defineFunction(stage.Function)
+	}
+
+	restoreVariables, err := e.setTemporaryVariables(stage.Assignments)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+	defer restoreVariables()
+
+	if fn, isFunction := e.lookupFunction(stage); isFunction {
+		return e.callFunction(fn, stage.Tokens[1:])
+	}
+
+	// source and exec, wired to the streams of the shell, which are already redirected
+	knownCmd, _ := e.lookupBuiltin(stage)
+	return e.execBuiltin(knownCmd, stage, e.stdio)
+}
+
+// executeCompound runs a control flow command or a group of commands and returns its exit status
+func (e *Executor) executeCompound(compound *types.CompoundCommand) int {
+	switch {
+	case compound.Group != nil:
+		return e.Execute(*compound.Group)
+	case compound.If != nil:
+		return e.executeIf(compound.If)
+	case compound.While != nil:
+		return e.executeWhile(compound.While)
+	case compound.For != nil:
+		return e.executeFor(compound.For)
+	case compound.ArithmeticFor != nil:
+		return e.executeArithmeticFor(compound.ArithmeticFor)
+	case compound.Case != nil:
+		return e.executeCase(compound.Case)
+	}
+
+	return statusSuccess
+}
+
+// executeIf runs the body of the first condition that succeeds, or the else body if none of them does
+// Its exit status is the one of the body that ran, 0 if none did
+func (e *Executor) executeIf(clause *types.IfClause) int {
+	for idx, condition := range clause.Conditions {
+		status := e.executeCondition(condition)
+		if e.stopped() {
+			return status
+		}
+
+		if status == statusSuccess {
+			return e.Execute(clause.Bodies[idx])
+		}
+	}
+
+	if clause.Else != nil {
+		return e.Execute(*clause.Else)
+	}
+
+	return statusSuccess
+}
+
+// executeCondition runs the condition of an if, while or until, whose failure does not end the shell with set -e
+func (e *Executor) executeCondition(condition types.ParsedPrompt) int {
+	e.conditionDepth++
+	defer func() { e.conditionDepth-- }()
+
+	return e.Execute(condition)
+}
+
+// executeWhile runs the body of a while loop as long as its condition succeeds, or of an until loop as long as it fails
+// Its exit status is the one of the last body that ran, 0 if none did
+func (e *Executor) executeWhile(clause *types.WhileClause) int {
+	e.loopDepth++
+	defer func() { e.loopDepth-- }()
+
+	status := statusSuccess
+	for {
+		conditionStatus := e.executeCondition(clause.Condition)
+		if e.leaveIteration() {
+			return status
+		}
+
+		if (conditionStatus == statusSuccess) == clause.Until {
+			return status
+		}
+
+		status = e.Execute(clause.Body)
+		if e.leaveIteration() {
+			return status
+		}
+	}
+}
+
+// executeFor runs the body of a for loop once for every field its words expand to, with the variable set to the field
+func (e *Executor) executeFor(clause *types.ForClause) int {
+	words, err := e.expandTokens(clause.Words)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+
+	e.loopDepth++
+	defer func() { e.loopDepth-- }()
+
+	status := statusSuccess
+	for _, word := range words {
+		if err := e.setVariable(clause.Name, word); err != nil {
+			fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+			return statusFailure
+		}
+
+		status = e.Execute(clause.Body)
+		if e.leaveIteration() {
+			break
+		}
+	}
+
+	return status
+}
+
+// executeArithmeticFor runs a C-style for loop: its init expression first, then its body as long as its condition
+// evaluates to a non zero value, followed each time by its update expression
+func (e *Executor) executeArithmeticFor(clause *types.ArithmeticForClause) int {
+	if _, err := e.evaluateOptionalArithmetic(clause.Init); err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+
+	e.loopDepth++
+	defer func() { e.loopDepth-- }()
+
+	status := statusSuccess
+	for {
+		condition, err := e.evaluateOptionalArithmetic(clause.Condition)
+		if err != nil {
+			fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+			return statusFailure
+		}
+
+		if condition == 0 {
+			return status
+		}
+
+		status = e.Execute(clause.Body)
+		if e.leaveIteration() {
+			return status
+		}
+
+		if _, err := e.evaluateOptionalArithmetic(clause.Update); err != nil {
+			fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+			return statusFailure
+		}
+	}
+}
+
+// evaluateOptionalArithmetic evaluates an expression of a C-style for loop, which is 1 when it is left empty
+func (e *Executor) evaluateOptionalArithmetic(expr string) (int64, error) {
+	if expr == "" {
+		return 1, nil
+	}
+
+	return e.evaluateArithmetic(expr)
+}
+
+// executeCase runs the body of the first item with a pattern matching the word, then depending on the terminator
+// of the item stops, runs the next body as well (;&) or goes on testing the patterns of the next items (;;&)
+func (e *Executor) executeCase(clause *types.CaseClause) int {
+	word, err := e.expandUnsplitWord(clause.Word)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+
+	status := statusSuccess
+	fallingThrough := false
+
+	for _, item := range clause.Items {
+		if !fallingThrough {
+			matches, err := e.matchCaseItem(item, word)
+			if err != nil {
+				fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+				return statusFailure
+			}
+
+			if !matches {
+				continue
+			}
+		}
+
+		status = statusSuccess
+		if len(item.Body.Chains) > 0 {
+			status = e.Execute(item.Body)
+		}
+
+		if item.Terminator == types.CaseBreak || e.stopped() {
+			return status
+		}
+		fallingThrough = item.Terminator == types.CaseFallthrough
+	}
+
+	return status
+}
+
+// matchCaseItem checks whether one of the patterns of a case item matches the word
+// The patterns are expanded without field splitting, their quoted parts matching literally
+func (e *Executor) matchCaseItem(item types.CaseItem, word string) (bool, error) {
+	for _, pattern := range item.Patterns {
+		expandedPattern, err := e.expandPattern(pattern)
+		if err != nil {
+			return false, err
+		}
+
+		if glob.Match(expandedPattern, word) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// stopped checks whether the commands following the current one must be skipped, after exit, return, break, continue or Ctrl+C
+func (e *Executor) stopped() bool {
+	return e.exited || e.returning || e.breakLoops > 0 || e.continueLoops > 0 || e.interrupted.Load()
+}
+
+// leaveIteration is called by a loop after running its condition or its body, telling whether it must stop
+// It consumes one of the loops left by break or continue, continue letting the last one go on with its next iteration
+func (e *Executor) leaveIteration() bool {
+	switch {
+	case e.exited || e.returning || e.interrupted.Load():
+		return true
+	case e.breakLoops > 0:
+		e.breakLoops--
+		return true
+	case e.continueLoops > 0:
+		e.continueLoops--
+		return e.continueLoops > 0
+	}
+
+	return false
+}
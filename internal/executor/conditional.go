@@ -0,0 +1,287 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/glob"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// rematchVariable is the array set by =~, holding the text matched by the regular expression, then by each of its groups
+const rematchVariable = "BASH_REMATCH"
+
+var errConditionalSyntax = errors.New("syntax error in conditional expression")
+
+// conditionalOperators are the unquoted words joining or grouping the tests of a conditional command
+var conditionalOperators = []string{types.OperatorAnd, types.OperatorOr, "(", ")"}
+
+// condition is a part of a conditional expression, its words being expanded only when it is evaluated,
+// so that && and || skip the expansions of the part they do not need
+type condition func() (bool, error)
+
+// conditionalParser builds the condition of a conditional command from its raw words, with the precedence:
+// !, then &&, then ||, and parentheses for grouping
+type conditionalParser struct {
+	e     *Executor
+	words []string
+	pos   int
+}
+
+// execConditional runs the conditional command [[ ... ]]
+// Its exit status is 0 if the expression is true, 1 if it is false and 2 if it is invalid or cannot be evaluated
+func (e *Executor) execConditional(words []string, s streams) int {
+	cp := conditionalParser{e: e, words: words}
+
+	cond, err := cp.parseOr()
+	if err == nil && cp.pos < len(words) {
+		err = fmt.Errorf("%w near `%s'", errConditionalSyntax, words[cp.pos])
+	}
+
+	var result bool
+	if err == nil {
+		result, err = cond()
+	}
+
+	if err != nil {
+		fmt.Fprintf(s.stderr, "gosh: %v\n", err)
+		return statusUsage
+	}
+
+	if !result {
+		return statusFailure
+	}
+	return statusSuccess
+}
+
+// parseOr parses conditions joined by ||
+func (cp *conditionalParser) parseOr() (condition, error) {
+	left, err := cp.parseAnd()
+	for err == nil && cp.accept(types.OperatorOr) {
+		var right condition
+		if right, err = cp.parseAnd(); err == nil {
+			left = orCondition(left, right)
+		}
+	}
+
+	return left, err
+}
+
+// parseAnd parses conditions joined by &&
+func (cp *conditionalParser) parseAnd() (condition, error) {
+	left, err := cp.parseNot()
+	for err == nil && cp.accept(types.OperatorAnd) {
+		var right condition
+		if right, err = cp.parseNot(); err == nil {
+			left = andCondition(left, right)
+		}
+	}
+
+	return left, err
+}
+
+// parseNot parses a condition negated by any number of !
+func (cp *conditionalParser) parseNot() (condition, error) {
+	if !cp.accept("!") {
+		return cp.parsePrimary()
+	}
+
+	cond, err := cp.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (bool, error) {
+		result, err := cond()
+		return !result, err
+	}, nil
+}
+
+// parsePrimary parses a parenthesized condition, a unary or binary test, or a single string which is true if not empty
+func (cp *conditionalParser) parsePrimary() (condition, error) {
+	word, err := cp.nextOperand()
+	if err != nil {
+		if cp.accept("(") {
+			return cp.parseGroup()
+		}
+		return nil, err
+	}
+
+	if builtins.IsUnaryTest(word) && cp.hasOperand() {
+		operand, _ := cp.nextOperand()
+		return cp.e.unaryCondition(word, operand), nil
+	}
+
+	if cp.pos < len(cp.words) && (builtins.IsBinaryTest(cp.words[cp.pos]) || cp.words[cp.pos] == "=~") {
+		operator := cp.words[cp.pos]
+		cp.pos++
+
+		right, err := cp.nextOperand()
+		if err != nil {
+			return nil, err
+		}
+		return cp.e.binaryCondition(operator, word, right), nil
+	}
+
+	return func() (bool, error) {
+		value, err := cp.e.expandUnsplitWord(word)
+		return value != "", err
+	}, nil
+}
+
+// parseGroup parses the rest of a parenthesized condition, up to its closing parenthesis
+func (cp *conditionalParser) parseGroup() (condition, error) {
+	cond, err := cp.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cp.accept(")") {
+		return nil, cp.syntaxError()
+	}
+	return cond, nil
+}
+
+// nextOperand consumes the next word, which must not be an operator joining or grouping conditions
+// The < and > operators are plain words outside of a binary test
+func (cp *conditionalParser) nextOperand() (string, error) {
+	if !cp.hasOperand() {
+		return "", cp.syntaxError()
+	}
+
+	cp.pos++
+	return cp.words[cp.pos-1], nil
+}
+
+// hasOperand checks whether the next word can be an operand
+func (cp *conditionalParser) hasOperand() bool {
+	return cp.pos < len(cp.words) && !slices.Contains(conditionalOperators, cp.words[cp.pos])
+}
+
+// accept consumes the next word if it is the given operator
+func (cp *conditionalParser) accept(operator string) bool {
+	if cp.pos < len(cp.words) && cp.words[cp.pos] == operator {
+		cp.pos++
+		return true
+	}
+	return false
+}
+
+// syntaxError returns the error for the word found where it cannot be, or for the expression ending too early
+func (cp *conditionalParser) syntaxError() error {
+	if cp.pos < len(cp.words) {
+		return fmt.Errorf("%w near `%s'", errConditionalSyntax, cp.words[cp.pos])
+	}
+	return fmt.Errorf("%w: unexpected end of expression", errConditionalSyntax)
+}
+
+func andCondition(left, right condition) condition {
+	return func() (bool, error) {
+		if result, err := left(); !result || err != nil {
+			return false, err
+		}
+		return right()
+	}
+}
+
+func orCondition(left, right condition) condition {
+	return func() (bool, error) {
+		if result, err := left(); result || err != nil {
+			return result, err
+		}
+		return right()
+	}
+}
+
+// unaryCondition returns the condition of a unary test, such as -f file or -z string, its operand being expanded without splitting
+func (e *Executor) unaryCondition(operator, word string) condition {
+	return func() (bool, error) {
+		operand, err := e.expandUnsplitWord(word)
+		if err != nil {
+			return false, err
+		}
+
+		return builtins.UnaryTest(operator, operand, e), nil
+	}
+}
+
+// binaryCondition returns the condition of a binary test
+// The right operand of ==, = and != is a pattern, the one of =~ a regular expression, their quoted parts matching literally
+// The operands of the integer comparisons are arithmetic expressions
+func (e *Executor) binaryCondition(operator, leftWord, rightWord string) condition {
+	return func() (bool, error) {
+		if slices.Contains(builtins.IntegerTests, operator) {
+			left, err := e.evaluateArithmetic(leftWord)
+			if err != nil {
+				return false, err
+			}
+
+			right, err := e.evaluateArithmetic(rightWord)
+			if err != nil {
+				return false, err
+			}
+
+			return builtins.CompareIntegers(operator, left, right), nil
+		}
+
+		left, err := e.expandUnsplitWord(leftWord)
+		if err != nil {
+			return false, err
+		}
+
+		switch operator {
+		case "==", "=", "!=":
+			pattern, err := e.expandPattern(rightWord)
+			if err != nil {
+				return false, err
+			}
+
+			return glob.Match(pattern, left) == (operator != "!="), nil
+		case "=~":
+			return e.matchRegex(left, rightWord)
+		}
+
+		right, err := e.expandUnsplitWord(rightWord)
+		if err != nil {
+			return false, err
+		}
+
+		return builtins.BinaryTest(operator, left, right, e)
+	}
+}
+
+// matchRegex matches a string against the regular expression of =~, which is unanchored
+// The text matched by the expression and by each of its groups is stored in BASH_REMATCH, which is emptied if it does not match
+func (e *Executor) matchRegex(value, word string) (bool, error) {
+	expr, err := e.expandRegex(word)
+	if err != nil {
+		return false, err
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid regular expression", expr)
+	}
+
+	match := re.FindStringSubmatch(value)
+	if err := e.vars.SetArray(rematchVariable, match); err != nil {
+		return false, err
+	}
+
+	return match != nil, nil
+}
+
+// expandRegex expands a word used as a regular expression, its quoted characters only matching themselves
+func (e *Executor) expandRegex(word string) (string, error) {
+	fb := fieldBuilder{escape: regexp.QuoteMeta}
+	if err := e.expandInto(word, "", &fb); err != nil {
+		return "", err
+	}
+
+	fb.finish()
+	return strings.Join(fb.patterns, " "), nil
+}
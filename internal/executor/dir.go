@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Dir returns the working directory of the shell, which is the one of the process
+// A subshell runs in the process of the shell as well, so it keeps a working directory of its own, which cd changes
+func (e *Executor) Dir() (string, error) {
+	if e.dir != "" {
+		return e.dir, nil
+	}
+
+	return os.Getwd()
+}
+
+// Chdir changes the working directory of the shell, or the one of the subshell, leaving the process alone
+func (e *Executor) Chdir(dir string) error {
+	if !e.isSubshell {
+		return os.Chdir(dir)
+	}
+
+	path := e.ResolvePath(dir)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return &os.PathError{Op: "chdir", Path: dir, Err: errors.Unwrap(err)}
+	}
+
+	if !info.IsDir() {
+		return &os.PathError{Op: "chdir", Path: dir, Err: syscall.ENOTDIR}
+	}
+
+	e.dir = filepath.Clean(path)
+	return nil
+}
+
+// ResolvePath returns the path of a file relative to the working directory of the subshell, which the process does not share
+// In the shell itself, the path is left as is
+func (e *Executor) ResolvePath(path string) string {
+	if e.dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(e.dir, path)
+}
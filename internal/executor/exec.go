@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/jobs"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// SetCleanup sets the function cleaning up the shell (e.g. restoring the terminal and closing the logger),
+// which runs before exec replaces the shell with its command
+func (e *Executor) SetCleanup(cleanup func()) {
+	e.cleanup = cleanup
+}
+
+// isExec checks whether a stage runs exec
+func isExec(stage types.PipelineStage) bool {
+	return len(stage.Tokens) > 0 && stage.Tokens[0] == builtins.BuiltinExec
+}
+
+// executeExec runs exec without a command in the shell itself, its redirections changing the streams of the shell for good,
+// which all the following commands are wired to. With a command, exec is a builtin replacing the shell, see Exec
+func (e *Executor) executeExec(stage types.PipelineStage) int {
+	s, files, err := e.applyRedirections(stage.Redirections, e.stdio)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+
+	e.setStdio(s, files)
+	return statusSuccess
+}
+
+// setStdio makes the given streams the ones of the shell, taking over the files opened for them
+// The files the shell took over before are closed once none of its streams refers to them anymore (e.g. after exec 3>&-)
+func (e *Executor) setStdio(s streams, files []io.Closer) {
+	e.stdio = s
+	e.files = append(e.files, files...)
+
+	fds := s.fdTable()
+
+	var kept []io.Closer
+	for _, file := range e.files {
+		isUsed := false
+		for _, stream := range fds {
+			if stream == any(file) {
+				isUsed = true
+				break
+			}
+		}
+
+		if isUsed {
+			kept = append(kept, file)
+		} else {
+			file.Close()
+		}
+	}
+	e.files = kept
+}
+
+// Exec runs the command of exec wired to the streams of the shell, which replaces the shell
+// A subshell shares its process with the shell, so the command runs as a child process instead and the subshell ends with it
+// A script cannot go on without the command replacing it, it ends if the command cannot run
+func (e *Executor) Exec(args []string) int {
+	cmd, err := e.prepareBinary(types.PipelineStage{Tokens: args}, e.stdio)
+	if err == nil {
+		err = cmd.Err
+	}
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "%s: %v\n", builtins.BuiltinExec, err)
+
+		if !e.interactive {
+			e.exited = true
+		}
+		if errors.Is(err, errNotFound) {
+			return statusNotFound
+		}
+		return statusNotExecutable
+	}
+
+	if e.isSubshell {
+		e.exited = true
+
+		job := e.jobs.NewJob(strings.Join(args, " "), false)
+		p, err := e.jobs.StartProcess(job, cmd, true)
+		if err != nil {
+			fmt.Fprintf(e.stdio.stderr, "%s: %v\n", builtins.BuiltinExec, err)
+			return statusNotExecutable
+		}
+
+		return e.jobs.WaitProcesses([]*jobs.Process{p})
+	}
+
+	e.replaceShell(cmd, e.stdio)
+	return statusNotExecutable
+}
@@ -0,0 +1,101 @@
+//go:build unix
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"golang.org/x/sys/unix"
+)
+
+// userFdLimit is the highest file descriptor left to the redirections of the user (e.g. exec 3>file), like other shells do
+// The descriptors the shell keeps open for itself are above it, so that exec can place the files of the user on them
+const userFdLimit = 9
+
+// ReserveFds makes the descriptors the Go runtime keeps open for itself (those of its network poller) land above userFdLimit
+// It holds the ones below while the poller starts, which opening a pipe does, so it must run before the shell opens any file
+func ReserveFds() {
+	var held []int
+	for {
+		fd, err := unix.Open(os.DevNull, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			break
+		}
+
+		held = append(held, fd)
+		if fd >= userFdLimit {
+			break
+		}
+	}
+
+	if reader, writer, err := os.Pipe(); err == nil {
+		reader.Close()
+		writer.Close()
+	}
+
+	for _, fd := range held {
+		unix.Close(fd)
+	}
+}
+
+// replaceShell replaces the process of the shell with the command, once the shell is cleaned up
+// The streams become the file descriptors of the process, which the command inherits
+// The shell cannot go on once it is cleaned up, it exits if the command fails to start
+func (e *Executor) replaceShell(cmd *exec.Cmd, s streams) {
+	if e.cleanup != nil {
+		e.cleanup()
+	}
+
+	err := installStreams(s)
+	if err == nil {
+		err = syscall.Exec(cmd.Path, cmd.Args, cmd.Env)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %s: %v\n", builtins.BuiltinExec, cmd.Args[0], err)
+	os.Exit(statusNotExecutable)
+}
+
+// installStreams places the files of the streams on the matching file descriptors of the process itself, closing the closed ones
+// The files are first duplicated above all these descriptors, so that placing one never overwrites another one still to be placed
+func installStreams(s streams) error {
+	fds := s.fdTable()
+
+	highest := types.Stderr
+	for fd := range fds {
+		highest = max(highest, fd)
+	}
+
+	copies := make(map[int]int, len(fds))
+	for fd, stream := range fds {
+		switch stream := stream.(type) {
+		case closedFd:
+		case *os.File:
+			dup, err := unix.FcntlInt(stream.Fd(), unix.F_DUPFD_CLOEXEC, highest+1)
+			if err != nil {
+				return err
+			}
+			copies[fd] = dup
+		default:
+			return fmt.Errorf("%d: %w", fd, errBadFd)
+		}
+	}
+
+	for fd := range fds {
+		dup, isOpen := copies[fd]
+		if !isOpen {
+			unix.Close(fd)
+			continue
+		}
+
+		if err := unix.Dup2(dup, fd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
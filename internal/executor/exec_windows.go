@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+)
+
+// ReserveFds does nothing, the files of a process are handles on Windows, which the runtime never hands out
+// in place of the descriptors of the user
+func ReserveFds() {}
+
+// replaceShell runs the command in place of the shell, once the shell is cleaned up
+// Windows cannot replace a process with another program, so the shell waits for the command and exits with its exit status
+func (e *Executor) replaceShell(cmd *exec.Cmd, _ streams) {
+	if e.cleanup != nil {
+		e.cleanup()
+	}
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		os.Exit(statusSuccess)
+	case errors.As(err, &exitErr):
+		os.Exit(exitErr.ExitCode())
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %s: %v\n", builtins.BuiltinExec, cmd.Args[0], err)
+	os.Exit(statusNotExecutable)
+}
@@ -1,38 +1,225 @@
 package executor
 
 import (
+	"context"
+	"io"
+	"maps"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/commands"
 	"github.com/SebastianRichiteanu/Gosh/internal/config"
-	"github.com/SebastianRichiteanu/Gosh/internal/logger"
+	"github.com/SebastianRichiteanu/Gosh/internal/jobs"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
 	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/variables"
 )
 
 type Executor struct {
+	ctx         context.Context // the context the builtins run with, cancelled by Ctrl+C
 	cfg         *config.Config
 	builtinCmds *types.CommandMap
-	logger      *logger.Logger
+	functions   *types.Functions
+	commands    *commands.Table
+	parser      *parser.Parser
+	jobs        *jobs.Table
+	options     *types.Options
+	vars        *variables.Store
+	traps       *traps.Table
+
+	lastStatus        int
+	lastBackgroundPid int
+	shellName         string
+	positionalArgs    []string
+	substitutions     int // the number of command substitutions run, telling whether an expansion ran one
+
+	stdio    streams     // the streams the commands are wired to, unless piped or redirected
+	files    []io.Closer // the files opened by exec for the streams, closed once no stream uses them anymore
+	terminal Terminal    // the terminal of an interactive shell, nil otherwise
+	cleanup  func()      // cleans up the shell before exec replaces it, nil if there is nothing to clean up
+
+	dir string // the working directory of a subshell, which runs in the process of the shell, empty in the shell itself
+
+	isSubshell  bool
+	interactive bool // whether the shell reads its commands from the user, known before the goshrc runs, unlike the terminal
+	exited      bool // whether exit was called in the subshell or a command failed with set -e, no more commands run after it
+
+	loopDepth     int // the number of loops running, which break and continue can leave
+	breakLoops    int // the number of loops break is still leaving
+	continueLoops int // the number of loops continue is still leaving, the last one going on with its next iteration
+
+	callDepth int  // the number of functions and sourced files running, which return can leave
+	returning bool // whether return was called, no more commands of the function or sourced file run after it
+
+	conditionDepth int // the number of conditions running (e.g. of an if), whose failures do not end the shell with set -e
+
+	runningTrap bool // whether a trapped command is running, which does not raise the DEBUG, ERR and RETURN traps
+
+	executeDepth int         // the number of command lists running, the outermost one being the line read by the shell
+	interrupted  atomic.Bool // whether Ctrl+C stopped the line, no more commands of it run. It is set by the signal handler as well
+
+	cancelMu sync.Mutex
+	cancel   context.CancelFunc // cancels the context of the line, which the signal handler does on Ctrl+C
+}
+
+// streams holds the streams a command is wired to
+type streams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	extraFiles []*os.File // the file descriptors following stderr, a nil entry being a closed one
 }
 
-func NewExecutor(builtinCmds *types.CommandMap, cfg *config.Config, logger *logger.Logger) *Executor {
+func NewExecutor(builtinCmds *types.CommandMap, functions *types.Functions, commandTable *commands.Table, parser *parser.Parser,
+	jobTable *jobs.Table, options *types.Options, vars *variables.Store, trapTable *traps.Table, cfg *config.Config) *Executor {
 	return &Executor{
+		ctx:         context.Background(),
 		cfg:         cfg,
 		builtinCmds: builtinCmds,
-		logger:      logger,
+		functions:   functions,
+		commands:    commandTable,
+		parser:      parser,
+		jobs:        jobTable,
+		options:     options,
+		vars:        vars,
+		traps:       trapTable,
+
+		shellName: os.Args[0],
+		stdio:     defaultStreams(),
 	}
 }
 
-// Execute executes the given command based on the parsed prompt
-func (e *Executor) Execute(prompt types.ParsedPrompt) {
-	if len(prompt.Tokens) == 0 {
-		return
+// subshell returns an executor starting from the state of this one, with its commands wired to the given streams
+// Its exit status, parameters, variables, functions and working directory are its own, they are not reported back
+func (e *Executor) subshell(stdio streams) *Executor {
+	functions := maps.Clone(*e.functions)
+	dir, _ := e.Dir()
+
+	return &Executor{
+		ctx:         e.ctx,
+		cfg:         e.cfg,
+		builtinCmds: e.builtinCmds,
+		functions:   &functions,
+		commands:    e.commands,
+		parser:      e.parser,
+		jobs:        e.jobs,
+		options:     e.options,
+		vars:        e.vars.Clone(),
+
+		lastStatus:        e.lastStatus,
+		lastBackgroundPid: e.lastBackgroundPid,
+		shellName:         e.shellName,
+		positionalArgs:    e.positionalArgs,
+		callDepth:         e.callDepth,
+		conditionDepth:    e.conditionDepth,
+
+		stdio:       stdio,
+		terminal:    e.terminal,
+		isSubshell:  true,
+		dir:         dir,
+		interactive: e.interactive,
 	}
+}
 
-	if e.builtinCmds != nil {
-		knownCmd, isKnownCmd := (*e.builtinCmds)[prompt.Tokens[0]]
-		if isKnownCmd {
-			e.execBuiltin(knownCmd, prompt)
-			return
+// Execute executes the given command based on the parsed prompt, running its chains one after the other
+// It returns the exit status of the last command, which is also available as $?
+func (e *Executor) Execute(prompt types.ParsedPrompt) int {
+	// A Ctrl+C received before the line started, e.g. at the prompt, was not meant for it
+	// The line gets a context of its own, which the blocking builtins (e.g. wait) give up on once Ctrl+C cancels it
+	// A subshell keeps the context of the line it runs in
+	if e.executeDepth == 0 {
+		e.interrupted.Store(false)
+
+		if !e.isSubshell {
+			defer e.newLineContext()()
+		}
+	}
+
+	e.executeDepth++
+	defer func() { e.executeDepth-- }()
+
+	for _, chain := range prompt.Chains {
+		if e.stopped() {
+			break
 		}
+
+		e.lastStatus = e.executeChain(chain)
+		e.RunPendingTraps()
 	}
 
-	e.execBinary(prompt)
+	// An interrupted line ends with the status of a command killed by SIGINT, even if only builtins ran
+	if e.interrupted.Load() {
+		e.lastStatus = statusSignalOffset + int(syscall.SIGINT)
+	}
+
+	return e.lastStatus
+}
+
+// SetInteractive marks the shell as reading its commands from the user, which it is told before its goshrc runs
+// An interactive shell goes on after the commands which end other shells, e.g. the expansion of an unset parameter
+func (e *Executor) SetInteractive() {
+	e.interactive = true
+}
+
+// SetArgs sets the name of the shell, $0, and its positional parameters, such as the path and the arguments of a script
+func (e *Executor) SetArgs(shellName string, args []string) {
+	e.shellName = shellName
+	e.positionalArgs = args
+}
+
+// Interrupt stops the line the shell runs, after Ctrl+C was pressed while it was running builtins only,
+// which the shell gets rather than a foreground job, or killed a job resumed by fg
+func (e *Executor) Interrupt() {
+	e.interrupted.Store(true)
+
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
+
+// newLineContext gives the line about to run a context of its own, returning the function cancelling it once the line is over
+func (e *Executor) newLineContext() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e.cancelMu.Lock()
+	defer e.cancelMu.Unlock()
+
+	e.ctx, e.cancel = ctx, cancel
+	return cancel
+}
+
+// Exited checks whether the shell must stop, after a command failed with set -e or a script expanded an unset parameter
+func (e *Executor) Exited() bool {
+	return e.exited
+}
+
+// NotifyJobs reports the background jobs that finished or were stopped since the last prompt
+func (e *Executor) NotifyJobs() {
+	e.jobs.Notify(e.stdio.stderr)
+}
+
+// lookupBuiltin returns the built-in command for the given stage, if there is one
+func (e *Executor) lookupBuiltin(stage types.PipelineStage) (types.Builtin, bool) {
+	if e.builtinCmds == nil || len(stage.Tokens) == 0 {
+		return nil, false
+	}
+
+	knownCmd, isKnownCmd := (*e.builtinCmds)[stage.Tokens[0]]
+	return knownCmd, isKnownCmd
+}
+
+// defaultStreams returns the streams of the shell process itself
+func defaultStreams() streams {
+	return streams{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
 }
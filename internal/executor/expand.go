@@ -0,0 +1,456 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/glob"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+)
+
+const defaultIFS = " \t\n"
+
+// fieldBuilder accumulates the fields resulting from the expansion of a word
+// Alongside every field, it builds the glob pattern of the field, in which only the unquoted characters keep their meaning
+type fieldBuilder struct {
+	fields  []string
+	current strings.Builder
+	started bool // whether the current field exists, even if it is empty (e.g. "")
+
+	patterns []string // the pattern of every field, matching only the field itself if it has no unquoted glob characters
+	pattern  strings.Builder
+	escape   func(string) string // escapes the quoted text in the patterns, glob.Escape if nil
+
+	literalIFS string // the characters splitting unquoted literal text too, as in the word of ${name:-word}
+}
+
+// write appends quoted text to the current field, its glob characters matching only themselves
+func (fb *fieldBuilder) write(text string) {
+	escape := glob.Escape
+	if fb.escape != nil {
+		escape = fb.escape
+	}
+
+	fb.current.WriteString(text)
+	fb.pattern.WriteString(escape(text))
+	fb.started = true
+}
+
+// writeUnquoted appends unquoted text to the current field, its glob characters being subject to pathname expansion
+func (fb *fieldBuilder) writeUnquoted(text string) {
+	fb.current.WriteString(text)
+	fb.pattern.WriteString(text)
+	fb.started = true
+}
+
+// split ends the current field, if there is one
+func (fb *fieldBuilder) split() {
+	if !fb.started {
+		return
+	}
+
+	fb.fields = append(fb.fields, fb.current.String())
+	fb.patterns = append(fb.patterns, fb.pattern.String())
+
+	fb.current.Reset()
+	fb.pattern.Reset()
+	fb.started = false
+}
+
+// writeSplit appends the result of an unquoted expansion, starting a new field on every separator made of IFS characters
+func (fb *fieldBuilder) writeSplit(value, ifs string) {
+	text := []rune(value)
+	isDelimiter := func(idx int) bool {
+		return strings.ContainsRune(ifs, text[idx])
+	}
+
+	for idx := 0; idx < len(text); {
+		if !isDelimiter(idx) {
+			fb.writeUnquoted(string(text[idx]))
+			idx++
+			continue
+		}
+
+		// A character of IFS other than whitespace ends a field even if it is empty, e.g. the middle one of 1::2
+		end, isExplicit := ifsSeparator(text, idx, isDelimiter)
+		if isExplicit {
+			fb.started = true
+		}
+
+		fb.split()
+		idx = end
+	}
+}
+
+// ifsSeparator returns the end of the field separator starting at idx, and whether it holds an IFS character other than whitespace
+// A separator is a run of IFS whitespace, holding at most one other IFS character which separates two fields, empty ones included
+// isDelimiter tells whether a character of the text is one of IFS, which the escaped ones are not
+func ifsSeparator(text []rune, idx int, isDelimiter func(int) bool) (int, bool) {
+	isBlank := func(idx int) bool {
+		return isDelimiter(idx) && strings.ContainsRune(defaultIFS, text[idx])
+	}
+
+	for idx < len(text) && isBlank(idx) {
+		idx++
+	}
+
+	if idx == len(text) || !isDelimiter(idx) {
+		return idx, false
+	}
+
+	idx++
+	for idx < len(text) && isBlank(idx) {
+		idx++
+	}
+
+	return idx, true
+}
+
+// finish ends the current field and returns all the fields
+func (fb *fieldBuilder) finish() []string {
+	fb.split()
+	return fb.fields
+}
+
+// expandTokens expands the raw tokens of a command into its final arguments, brace expansion coming first
+func (e *Executor) expandTokens(tokens []string) ([]string, error) {
+	var args []string
+
+	for _, token := range tokens {
+		for _, word := range expandBraces(token) {
+			fields, err := e.expandWord(word)
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, fields...)
+		}
+	}
+
+	return args, nil
+}
+
+// expandSingleWord expands a word that must result in exactly one field, such as a redirection target
+func (e *Executor) expandSingleWord(word string) (string, error) {
+	fields, err := e.expandWord(word)
+	if err != nil {
+		return "", err
+	}
+
+	if len(fields) != 1 {
+		return "", fmt.Errorf("%s: ambiguous redirect", word)
+	}
+
+	return fields[0], nil
+}
+
+// expandUnsplitWord expands a word without splitting it into fields, such as the word of a here-string
+func (e *Executor) expandUnsplitWord(word string) (string, error) {
+	fields, err := e.expandFields(word, "")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// expandWord performs tilde expansion, parameter expansion, command substitution, arithmetic expansion,
+// field splitting, pathname expansion and quote removal on a raw word
+func (e *Executor) expandWord(word string) ([]string, error) {
+	ifs, isSet := e.vars.Lookup("IFS")
+	if !isSet {
+		ifs = defaultIFS
+	}
+
+	var fb fieldBuilder
+	if err := e.expandInto(word, ifs, &fb); err != nil {
+		return nil, err
+	}
+
+	fields := fb.finish()
+	return e.expandPathnames(fields, fb.patterns)
+}
+
+// expandFields expands a raw word, splitting the results of unquoted expansions on the characters of ifs
+func (e *Executor) expandFields(word, ifs string) ([]string, error) {
+	var fb fieldBuilder
+	if err := e.expandInto(word, ifs, &fb); err != nil {
+		return nil, err
+	}
+
+	return fb.finish(), nil
+}
+
+// expandInto expands a raw word into the field builder
+func (e *Executor) expandInto(word, ifs string, fb *fieldBuilder) error {
+	inSingleQuote := false
+	inDoubleQuote := false
+	emptyQuotedExpansion := false // "$@" without positional parameters results in no field at all
+
+	if strings.HasPrefix(word, "~") {
+		home, rest := e.expandTilde(word)
+		fb.write(home)
+		word = rest
+	}
+
+	for i := 0; i < len(word); i++ {
+		char := word[i]
+
+		switch {
+		case char == '\\' && !inSingleQuote:
+			if i+1 >= len(word) {
+				fb.write(string(char))
+				continue
+			}
+
+			next := word[i+1]
+			if inDoubleQuote && !strings.ContainsRune("$`\"\\\n", rune(next)) {
+				fb.write(string(char)) // Inside double quotes, only some characters can be escaped
+				continue
+			}
+
+			fb.write(string(next))
+			i++
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+			fb.started = true
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+			if !inDoubleQuote && !emptyQuotedExpansion {
+				fb.started = true
+			}
+			emptyQuotedExpansion = false
+		case char == '`' && !inSingleQuote:
+			consumed, err := e.expandSubstitution(word[i:], fb, inDoubleQuote, ifs)
+			if err != nil {
+				return err
+			}
+
+			i += consumed - 1
+		case char == '$' && !inSingleQuote:
+			consumed, err := e.expandDollar(word[i:], fb, inDoubleQuote, ifs)
+			if err != nil {
+				return err
+			}
+
+			if consumed == 0 {
+				// Not an expansion, treat as literal, still unquoted as the end anchor of a regular expression
+				if inDoubleQuote {
+					fb.write(string(char))
+				} else {
+					fb.writeUnquoted(string(char))
+				}
+				continue
+			}
+
+			if inDoubleQuote && len(e.positionalArgs) == 0 && (strings.HasPrefix(word[i:], "$@") || strings.HasPrefix(word[i:], "${@}")) {
+				emptyQuotedExpansion = true
+			}
+
+			i += consumed - 1
+		case inSingleQuote || inDoubleQuote:
+			fb.write(string(char))
+		case strings.IndexByte(fb.literalIFS, char) != -1:
+			fb.split()
+		default:
+			fb.writeUnquoted(string(char))
+		}
+	}
+
+	return nil
+}
+
+// expandHereDoc performs parameter expansion and command substitution on the body of a here-document
+// Quotes are kept as is, and a backslash only escapes $, ` and \ or removes the newline following it
+func (e *Executor) expandHereDoc(body string) (string, error) {
+	var fb fieldBuilder
+
+	for i := 0; i < len(body); i++ {
+		char := body[i]
+
+		switch {
+		case char == '\\' && i+1 < len(body) && strings.ContainsRune("$`\\\n", rune(body[i+1])):
+			if body[i+1] != '\n' {
+				fb.write(string(body[i+1]))
+			}
+			i++
+		case char == '`':
+			consumed, err := e.expandSubstitution(body[i:], &fb, true, "")
+			if err != nil {
+				return "", err
+			}
+
+			i += consumed - 1
+		case char == '$':
+			consumed, err := e.expandDollar(body[i:], &fb, true, "")
+			if err != nil {
+				return "", err
+			}
+
+			if consumed == 0 {
+				fb.write(string(char))
+				continue
+			}
+
+			i += consumed - 1
+		default:
+			fb.write(string(char))
+		}
+	}
+
+	return strings.Join(fb.finish(), " "), nil
+}
+
+// expandTilde expands a leading `~` or `~user` to the corresponding home directory
+// It returns the expanded prefix and the rest of the word
+func (e *Executor) expandTilde(word string) (string, string) {
+	end := strings.IndexByte(word, '/')
+	if end == -1 {
+		end = len(word)
+	}
+
+	name := word[1:end]
+	if name == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", word
+		}
+		return home, word[end:]
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", word // Unknown user, keep the word as is
+	}
+
+	return u.HomeDir, word[end:]
+}
+
+// expandDollar expands the parameter starting at the `$` at the beginning of the input, writing its value to the field builder
+// It returns the number of bytes consumed from the input, 0 meaning there was no parameter to expand
+func (e *Executor) expandDollar(input string, fb *fieldBuilder, quoted bool, ifs string) (int, error) {
+	if len(input) < 2 {
+		return 0, nil
+	}
+
+	var name string
+	var consumed int
+
+	switch next := input[1]; {
+	case next == '(':
+		if _, isArithmetic := parser.ArithmeticLength(input[1:]); isArithmetic {
+			return e.expandArithmetic(input, fb, quoted, ifs)
+		}
+		return e.expandSubstitution(input, fb, quoted, ifs)
+	case next == '{':
+		return e.expandParameter(input, fb, quoted, ifs)
+	case isSpecialParameter(next) || isDigit(next):
+		name = input[1:2]
+		consumed = 2
+	case isAlpha(next):
+		end := 2
+		for end < len(input) && isAlphaNumeric(input[end]) {
+			end++
+		}
+
+		name = input[1:end]
+		consumed = end
+	default:
+		return 0, nil
+	}
+
+	if name == "@" || name == "*" {
+		writeList(name, e.positionalArgs, fb, quoted, ifs)
+		return consumed, nil
+	}
+
+	value, isSet := e.lookupParameter(name)
+	if err := e.checkBound(name, isSet); err != nil {
+		return 0, err
+	}
+
+	if quoted {
+		fb.write(value)
+	} else {
+		fb.writeSplit(value, ifs)
+	}
+
+	return consumed, nil
+}
+
+// writeList writes a list of values expanded from $@ or $*, such as the positional parameters
+// Quoted, "$@" results in a field for every value while "$*" joins them using the first character of IFS
+func writeList(name string, values []string, fb *fieldBuilder, quoted bool, ifs string) {
+	if quoted && name == "*" {
+		separator := ""
+		if ifs != "" {
+			separator = ifs[:1]
+		}
+
+		fb.write(strings.Join(values, separator))
+		return
+	}
+
+	for idx, value := range values {
+		if idx > 0 {
+			fb.split()
+		}
+
+		if quoted {
+			fb.write(value)
+		} else {
+			fb.writeSplit(value, ifs)
+		}
+	}
+}
+
+// lookupParameter returns the value of a special parameter, a positional parameter or a variable
+func (e *Executor) lookupParameter(name string) (string, bool) {
+	switch name {
+	case "?":
+		return strconv.Itoa(e.lastStatus), true
+	case "$":
+		return strconv.Itoa(os.Getpid()), true
+	case "!":
+		if e.lastBackgroundPid == 0 {
+			return "", false
+		}
+		return strconv.Itoa(e.lastBackgroundPid), true
+	case "0":
+		return e.shellName, true
+	case "#":
+		return strconv.Itoa(len(e.positionalArgs)), true
+	case "@", "*":
+		return strings.Join(e.positionalArgs, " "), len(e.positionalArgs) > 0
+	}
+
+	if position, err := strconv.Atoi(name); err == nil {
+		if position < 1 || position > len(e.positionalArgs) {
+			return "", false
+		}
+		return e.positionalArgs[position-1], true
+	}
+
+	return e.vars.Lookup(name)
+}
+
+func isSpecialParameter(b byte) bool {
+	return strings.IndexByte("?$!#@*", b) != -1
+}
+
+func isAlpha(b byte) bool {
+	return (b >= 'a' && b <= 'z') ||
+		(b >= 'A' && b <= 'Z') ||
+		b == '_'
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isAlphaNumeric(b byte) bool {
+	return isAlpha(b) || isDigit(b)
+}
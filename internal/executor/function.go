@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// lookupFunction returns the shell function called by the given stage, if there is one
+// Functions come before the builtins and the binaries with the same name
+func (e *Executor) lookupFunction(stage types.PipelineStage) (types.FunctionDefinition, bool) {
+	if e.functions == nil || len(stage.Tokens) == 0 {
+		return types.FunctionDefinition{}, false
+	}
+
+	fn, isFunction := (*e.functions)[stage.Tokens[0]]
+	return fn, isFunction
+}
+
+// defineFunction stores a function definition, replacing any function with the same name
+func (e *Executor) defineFunction(fn *types.FunctionDefinition) int {
+	(*e.functions)[fn.Name] = *fn
+	return statusSuccess
+}
+
+// callFunction runs the body of a function in the shell itself, with the arguments as positional parameters
+// and a scope of its own for its local variables. The loops of the caller cannot be left from inside the function
+func (e *Executor) callFunction(fn types.FunctionDefinition, args []string) int {
+	positionalArgs, loopDepth := e.positionalArgs, e.loopDepth
+	e.positionalArgs, e.loopDepth = args, 0
+	e.callDepth++
+	e.vars.PushScope()
+
+	defer func() {
+		e.RunTrap(traps.Return)
+
+		e.vars.PopScope()
+		e.callDepth--
+		e.positionalArgs, e.loopDepth = positionalArgs, loopDepth
+		e.returning = false
+	}()
+
+	return e.executeInShell(fn.Body)
+}
@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/glob"
+)
+
+// expandPathnames replaces the fields holding unquoted glob characters by the paths they match
+// A pattern matching nothing is kept as is, unless the nullglob or failglob options are set
+// With the noglob option, the fields are all kept as is
+func (e *Executor) expandPathnames(fields, patterns []string) ([]string, error) {
+	if e.options != nil && e.options.Noglob {
+		return fields, nil
+	}
+
+	var expanded []string
+
+	for idx, field := range fields {
+		if !glob.HasMeta(patterns[idx]) {
+			expanded = append(expanded, field)
+			continue
+		}
+
+		matches := glob.Expand(patterns[idx], glob.Options{Dotglob: e.options != nil && e.options.Dotglob, Dir: e.dir})
+		if len(matches) > 0 {
+			expanded = append(expanded, matches...)
+			continue
+		}
+
+		switch {
+		case e.options != nil && e.options.Failglob:
+			return nil, fmt.Errorf("no match: %s", field)
+		case e.options != nil && e.options.Nullglob:
+			continue
+		}
+
+		expanded = append(expanded, field)
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,583 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/glob"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+)
+
+// parameterOperators are the operators of ${name<operator>word}, the longer ones coming first
+// The operators starting with a colon also treat a parameter set to an empty value as unset
+var parameterOperators = []string{
+	":-", ":=", ":?", ":+", "-", "=", "?", "+",
+	"##", "#", "%%", "%", "//", "/#", "/%", "/",
+	"^^", "^", ",,", ",", ":",
+}
+
+// settingOperators are the operators of ${name<operator>word} whose result depends on whether the parameter is set
+var settingOperators = []string{":-", "-", ":=", "=", ":?", "?", ":+", "+"}
+
+// parameterExpansion is the content of a ${...} expansion
+type parameterExpansion struct {
+	name     string
+	index    string // the subscript of ${name[index]}, @ or * standing for all the elements of an array
+	length   bool   // ${#name}, the length of the value
+	operator string // the operator applied to the value, if any
+	word     string // the raw word following the operator
+}
+
+// expandParameter expands the ${...} parameter expansion at the beginning of the input, writing its value to the field builder
+// It returns the number of bytes consumed from the input, 0 meaning there was no parameter to expand
+func (e *Executor) expandParameter(input string, fb *fieldBuilder, quoted bool, ifs string) (int, error) {
+	length, err := parser.ParameterLength(input, quoted)
+	if err != nil {
+		return 0, nil // End not found, treat as literal
+	}
+
+	pe, isValid := parseParameterExpansion(input[2 : length-1])
+	if !isValid {
+		return 0, fmt.Errorf("%s: bad substitution", input[:length])
+	}
+
+	isList := pe.isList()
+	values, isSet, err := e.parameterValues(pe.name, pe.index)
+	if err != nil {
+		return 0, err
+	}
+
+	// The operators testing whether the parameter is set can be used with set -u
+	if !slices.Contains(settingOperators, pe.operator) {
+		if err := e.checkBound(pe.name, isSet); err != nil {
+			return 0, err
+		}
+	}
+
+	if pe.length {
+		count := len(values)
+		if !isList {
+			count = utf8.RuneCountInString(values[0])
+		}
+
+		fb.write(strconv.Itoa(count))
+		return length, nil
+	}
+
+	isUnset := !isSet || (strings.HasPrefix(pe.operator, ":") && strings.Join(values, "") == "")
+
+	// The word of :-, - and :+, + is expanded in place of the parameter, keeping its own quotes
+	switch pe.operator {
+	case ":-", "-":
+		if isUnset {
+			return length, e.expandOperand(pe.word, fb, quoted, ifs)
+		}
+	case ":+", "+":
+		if !isUnset {
+			return length, e.expandOperand(pe.word, fb, quoted, ifs)
+		}
+
+		if quoted {
+			fb.write("")
+		}
+		return length, nil
+	case "":
+	default:
+		if values, err = e.applyParameterOperator(pe, values, isUnset, quoted); err != nil {
+			return 0, err
+		}
+	}
+
+	switch {
+	case isList:
+		writeList(pe.listName(), values, fb, quoted, ifs)
+	case quoted:
+		fb.write(strings.Join(values, " "))
+	default:
+		fb.writeSplit(strings.Join(values, " "), ifs)
+	}
+
+	return length, nil
+}
+
+// parseParameterExpansion parses the content of ${...}, reporting whether it is valid
+func parseParameterExpansion(content string) (parameterExpansion, bool) {
+	// ${#} is the number of positional parameters, while ${#name} is the length of a parameter
+	// and ${#name[@]} the number of elements of an array
+	if len(content) > 1 && content[0] == '#' {
+		name := parameterName(content[1:])
+		index, rest, isValid := splitSubscript(name, content[1+len(name):])
+		if name != "" && isValid && rest == "" {
+			return parameterExpansion{name: name, index: index, length: true}, true
+		}
+	}
+
+	name := parameterName(content)
+	if name == "" {
+		return parameterExpansion{}, false
+	}
+
+	index, rest, isValid := splitSubscript(name, content[len(name):])
+	if !isValid {
+		return parameterExpansion{}, false
+	}
+
+	if rest == "" {
+		return parameterExpansion{name: name, index: index}, true
+	}
+
+	operator := matchParameterOperator(rest)
+	if operator == "" {
+		return parameterExpansion{}, false
+	}
+
+	return parameterExpansion{name: name, index: index, operator: operator, word: rest[len(operator):]}, true
+}
+
+// splitSubscript splits the [index] following the name of a variable in ${...} from the rest of the content
+// It reports whether the subscript is valid, which it is when there is none
+func splitSubscript(name, rest string) (string, string, bool) {
+	if !strings.HasPrefix(rest, "[") || !parser.IsValidName(name) {
+		return "", rest, true
+	}
+
+	end := strings.IndexByte(rest, ']')
+	if end <= 1 {
+		return "", rest, false
+	}
+
+	return rest[1:end], rest[end+1:], true
+}
+
+// isList checks whether the expansion results in a list of values: $@, $* or all the elements of an array
+func (pe parameterExpansion) isList() bool {
+	return pe.name == "@" || pe.name == "*" || pe.index == "@" || pe.index == "*"
+}
+
+// listName returns @ or *, which tells how a list of values is joined inside double quotes
+func (pe parameterExpansion) listName() string {
+	if pe.index != "" {
+		return pe.index
+	}
+	return pe.name
+}
+
+// parameterName returns the name at the beginning of the content of ${...}
+func parameterName(content string) string {
+	if content == "" {
+		return ""
+	}
+
+	if isSpecialParameter(content[0]) {
+		return content[:1]
+	}
+
+	end := 0
+	if isDigit(content[0]) {
+		for end < len(content) && isDigit(content[end]) {
+			end++
+		}
+		return content[:end]
+	}
+
+	if !isAlpha(content[0]) {
+		return ""
+	}
+
+	for end < len(content) && isAlphaNumeric(content[end]) {
+		end++
+	}
+	return content[:end]
+}
+
+func matchParameterOperator(input string) string {
+	for _, operator := range parameterOperators {
+		if strings.HasPrefix(input, operator) {
+			return operator
+		}
+	}
+	return ""
+}
+
+// checkBound fails for an unset parameter with set -u, except for $@ and $* which may hold no value
+func (e *Executor) checkBound(name string, isSet bool) error {
+	if isSet || e.options == nil || !e.options.Nounset || name == "@" || name == "*" {
+		return nil
+	}
+
+	return e.unsetError(name, "unbound variable")
+}
+
+// unsetError returns the error of expanding an unset parameter, with set -u or ${name?message}
+// A shell which is not interactive cannot go on past it, it exits once the command fails
+func (e *Executor) unsetError(name, message string) error {
+	if !e.interactive {
+		e.exited = true
+	}
+
+	return fmt.Errorf("%s: %s", name, message)
+}
+
+// parameterValues returns the values of a parameter, or of the elements of an array given a subscript, and whether it is set
+// $@ and $* hold a value for every positional parameter, as do name[@] and name[*] for every element of an array,
+// any other parameter holds a single value. The subscript of a single element is an arithmetic expression
+func (e *Executor) parameterValues(name, index string) ([]string, bool, error) {
+	if name == "@" || name == "*" {
+		return append([]string(nil), e.positionalArgs...), len(e.positionalArgs) > 0, nil
+	}
+
+	if index == "" {
+		value, isSet := e.lookupParameter(name)
+		return []string{value}, isSet, nil
+	}
+
+	v, _ := e.vars.Get(name)
+	elements := v.Elements
+	if elements == nil && v.IsSet {
+		elements = []string{v.Value} // A scalar is an array holding a single element
+	}
+
+	if index == "@" || index == "*" {
+		return elements, len(elements) > 0, nil
+	}
+
+	position, err := e.evaluateArithmetic(index)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if position < 0 {
+		position += int64(len(elements))
+	}
+	if position < 0 || position >= int64(len(elements)) {
+		return []string{""}, false, nil
+	}
+	return []string{elements[position]}, true, nil
+}
+
+// applyParameterOperator applies the operator of a parameter expansion to the values of the parameter
+func (e *Executor) applyParameterOperator(pe parameterExpansion, values []string, isUnset, quoted bool) ([]string, error) {
+	switch pe.operator {
+	case ":=", "=":
+		if !isUnset {
+			return values, nil
+		}
+
+		if !parser.IsValidName(pe.name) {
+			return nil, fmt.Errorf("$%s: cannot assign in this way", pe.name)
+		}
+
+		word, err := e.expandOperandText(pe.word, quoted)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := e.setVariable(pe.name, word); err != nil {
+			return nil, err
+		}
+		return []string{word}, nil
+	case ":?", "?":
+		if !isUnset {
+			return values, nil
+		}
+
+		message, err := e.expandOperandText(pe.word, quoted)
+		if err != nil {
+			return nil, err
+		}
+
+		if message == "" {
+			message = "parameter not set"
+			if pe.operator == ":?" {
+				message = "parameter null or not set"
+			}
+		}
+		return nil, e.unsetError(pe.name, message)
+	case ":":
+		return e.substring(pe, values)
+	}
+
+	transform, err := e.parameterTransform(pe)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, value := range values {
+		values[idx] = transform(value)
+	}
+
+	return values, nil
+}
+
+// expandOperand expands the word substituted for a parameter by the :-, -, :+ and + operators into the field builder
+// Inside double quotes, the word is expanded as if it was double-quoted itself
+func (e *Executor) expandOperand(word string, fb *fieldBuilder, quoted bool, ifs string) error {
+	if quoted {
+		return e.expandInto(`"`+word+`"`, ifs, fb)
+	}
+
+	// Unquoted, the result of the expansion is split into fields, including the literal text of the word
+	literalIFS := fb.literalIFS
+	fb.literalIFS = ifs
+	defer func() { fb.literalIFS = literalIFS }()
+
+	return e.expandInto(word, ifs, fb)
+}
+
+// expandOperandText expands the word of an operator to a single string, such as the value assigned by :=
+func (e *Executor) expandOperandText(word string, quoted bool) (string, error) {
+	if quoted {
+		word = `"` + word + `"`
+	}
+
+	return e.expandUnsplitWord(word)
+}
+
+// parameterTransform returns the function applying a pattern operator of a parameter expansion to a value,
+// removing a prefix or a suffix, replacing a pattern or changing the case of letters
+func (e *Executor) parameterTransform(pe parameterExpansion) (func(string) string, error) {
+	switch pe.operator {
+	case "#", "##", "%", "%%":
+		pattern, err := e.expandPattern(pe.word)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(value string) string {
+			return removeAffix(value, pattern, pe.operator)
+		}, nil
+	case "/", "//", "/#", "/%":
+		rawPattern, rawReplacement := splitReplacement(pe.word)
+
+		pattern, err := e.expandPattern(rawPattern)
+		if err != nil {
+			return nil, err
+		}
+
+		replacement, err := e.expandUnsplitWord(rawReplacement)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(value string) string {
+			return replacePattern(value, pattern, replacement, pe.operator)
+		}, nil
+	}
+
+	// ^^, ^, ,, and , change the case of the letters matching the pattern, every letter if there is none
+	pattern, err := e.expandPattern(pe.word)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(value string) string {
+		return changeCase(value, pattern, pe.operator)
+	}, nil
+}
+
+// substring expands ${name:offset} and ${name:offset:length}, where offset and length are arithmetic expressions
+// A negative offset counts from the end of the value, and a negative length stops that many characters before the end
+// For $@ and $*, the offset and length count positional parameters, $0 being at offset 0
+func (e *Executor) substring(pe parameterExpansion, values []string) ([]string, error) {
+	offsetExpr, lengthExpr, hasLength := strings.Cut(pe.word, ":")
+
+	offset, err := e.evaluateArithmetic(offsetExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []string
+	switch {
+	case pe.name == "@" || pe.name == "*":
+		items = append([]string{e.shellName}, values...)
+	case pe.isList():
+		items = values
+	default:
+		for _, r := range values[0] {
+			items = append(items, string(r))
+		}
+	}
+
+	count := int64(len(items))
+	if offset < 0 {
+		offset += count
+	}
+	if offset < 0 || offset > count {
+		return nil, nil
+	}
+
+	end := count
+	if hasLength {
+		length, err := e.evaluateArithmetic(lengthExpr)
+		if err != nil {
+			return nil, err
+		}
+
+		if length < 0 {
+			end = count + length
+			if end < offset {
+				return nil, fmt.Errorf("%s: substring expression < 0", lengthExpr)
+			}
+		} else {
+			end = min(offset+length, count)
+		}
+	}
+
+	items = items[offset:end]
+	if pe.isList() {
+		return items, nil
+	}
+	return []string{strings.Join(items, "")}, nil
+}
+
+// expandPattern expands a word used as a pattern, its quoted characters only matching themselves
+func (e *Executor) expandPattern(word string) (string, error) {
+	var fb fieldBuilder
+	if err := e.expandInto(word, "", &fb); err != nil {
+		return "", err
+	}
+
+	fb.finish()
+	return strings.Join(fb.patterns, " "), nil
+}
+
+// splitReplacement splits the word of ${name/pattern/replacement} on the first unquoted slash
+func splitReplacement(word string) (string, string) {
+	inSingleQuote := false
+	inDoubleQuote := false
+
+	for i := 0; i < len(word); i++ {
+		switch char := word[i]; {
+		case char == '\\' && !inSingleQuote:
+			i++
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		case char == '/' && !inSingleQuote && !inDoubleQuote:
+			return word[:i], word[i+1:]
+		}
+	}
+
+	return word, ""
+}
+
+// removeAffix removes the prefix (# and ##) or suffix (% and %%) of a value matching a pattern,
+// the shortest one for # and %, the longest one for ## and %%
+func removeAffix(value, pattern, operator string) string {
+	bounds := runeBounds(value)
+
+	for idx := range bounds {
+		switch operator {
+		case "#":
+			if end := bounds[idx]; glob.Match(pattern, value[:end]) {
+				return value[end:]
+			}
+		case "##":
+			if end := bounds[len(bounds)-1-idx]; glob.Match(pattern, value[:end]) {
+				return value[end:]
+			}
+		case "%":
+			if start := bounds[len(bounds)-1-idx]; glob.Match(pattern, value[start:]) {
+				return value[:start]
+			}
+		case "%%":
+			if start := bounds[idx]; glob.Match(pattern, value[start:]) {
+				return value[:start]
+			}
+		}
+	}
+
+	return value
+}
+
+// replacePattern replaces the longest match of a pattern in a value, the first one for /, all of them for //,
+// the one at the start of the value for /# and the one at its end for /%
+func replacePattern(value, pattern, replacement, operator string) string {
+	if pattern == "" {
+		return value
+	}
+
+	bounds := runeBounds(value)
+
+	switch operator {
+	case "/#":
+		for idx := len(bounds) - 1; idx >= 0; idx-- {
+			if glob.Match(pattern, value[:bounds[idx]]) {
+				return replacement + value[bounds[idx]:]
+			}
+		}
+		return value
+	case "/%":
+		for _, start := range bounds {
+			if glob.Match(pattern, value[start:]) {
+				return value[:start] + replacement
+			}
+		}
+		return value
+	}
+
+	var sb strings.Builder
+	copied := 0
+
+	for idx := 0; idx < len(bounds)-1; idx++ {
+		start := bounds[idx]
+
+		end := -1
+		for next := len(bounds) - 1; next > idx; next-- {
+			if glob.Match(pattern, value[start:bounds[next]]) {
+				end = next
+				break
+			}
+		}
+
+		if end == -1 {
+			continue
+		}
+
+		sb.WriteString(value[copied:start])
+		sb.WriteString(replacement)
+		copied = bounds[end]
+
+		if operator == "/" {
+			break
+		}
+		idx = end - 1
+	}
+
+	sb.WriteString(value[copied:])
+	return sb.String()
+}
+
+// changeCase converts the letters of a value matching a pattern to uppercase (^^) or lowercase (,,),
+// only the first letter being converted by ^ and ,
+func changeCase(value, pattern, operator string) string {
+	convert := unicode.ToUpper
+	if operator[0] == ',' {
+		convert = unicode.ToLower
+	}
+
+	var sb strings.Builder
+	for idx, r := range value {
+		if (len(operator) == 2 || idx == 0) && (pattern == "" || glob.Match(pattern, string(r))) {
+			r = convert(r)
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// runeBounds returns the positions in a string where a character starts, followed by the length of the string
+func runeBounds(value string) []int {
+	bounds := make([]int, 0, len(value)+1)
+	for idx := range value {
+		bounds = append(bounds, idx)
+	}
+
+	return append(bounds, len(value))
+}
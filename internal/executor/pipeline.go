@@ -0,0 +1,315 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/jobs"
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// pipelineStage holds a stage of a pipeline together with the pipe ends it owns
+// and must close once it finishes, so the neighbouring stages see EOF or a broken pipe
+type pipelineStage struct {
+	shell   *Executor // the shell the stage expands and runs in, a subshell of its own in a pipeline of several commands
+	stage   types.PipelineStage
+	streams streams
+	closers []io.Closer
+
+	status int // the exit status of a stage without a command, which is the one of its last command substitution
+}
+
+// executePipeline runs all the stages of a pipeline concurrently, wiring the stdout of each stage
+// to the stdin of the next one, and returns the exit status of the last stage
+// Outside of a background job, the pipeline is a foreground job of its own which can be stopped with Ctrl+Z
+func (e *Executor) executePipeline(p types.Pipeline, job *jobs.Job) int {
+	foreground := job == nil
+
+	e.RunTrap(traps.Debug)
+
+	// A compound command, a function or source on its own runs in the shell itself, so that the variables it sets remain set
+	if foreground && len(p.Stages) == 1 && e.runsInShell(p.Stages[0]) {
+		return e.executeInShell(p.Stages[0])
+	}
+
+	// A subshell shares the process group of the shell, so that the shell gets the terminal signals while it runs
+	if foreground && e.isSubshell {
+		job = e.jobs.NewJob(p.Text, false)
+	} else if foreground {
+		job = e.jobs.NewForegroundJob(p.Text)
+	}
+
+	processes, err := e.startPipeline(p, job, true)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %v\n", err)
+		return statusFailure
+	}
+
+	if foreground {
+		status, stopped := e.jobs.WaitForeground(job)
+
+		// Ctrl+C meant to stop the whole line, not only the command it killed
+		if e.interactive && e.jobs.Interrupted(job) {
+			e.interrupted.Store(true)
+		}
+
+		if stopped || !e.pipefail() {
+			return status
+		}
+		return e.jobs.PipefailStatus(processes)
+	}
+
+	status := e.jobs.WaitProcesses(processes)
+	if e.pipefail() {
+		return e.jobs.PipefailStatus(processes)
+	}
+	return status
+}
+
+// pipefail checks whether a pipeline fails as soon as any of its commands fails, with set -o pipefail
+func (e *Executor) pipefail() bool {
+	return e.options != nil && e.options.Pipefail
+}
+
+// startPipeline expands the stages of a pipeline and starts them as members of the job, without waiting for them
+// If final is set, the exit status of the last stage becomes the exit status of the job
+func (e *Executor) startPipeline(p types.Pipeline, job *jobs.Job, final bool) ([]*jobs.Process, error) {
+	pipeline := make([]pipelineStage, len(p.Stages))
+	for idx, stage := range p.Stages {
+		// Each command of a pipeline runs in a subshell, so that the builtins leave the shell as it was (e.g. cd / | cat)
+		shell := e
+		if len(p.Stages) > 1 {
+			shell = e.subshell(e.stdio)
+		}
+
+		substitutions := shell.substitutions
+
+		expandedStage, err := shell.expandStage(stage)
+		if err != nil {
+			return nil, err
+		}
+
+		shell.trace(expandedStage)
+
+		pipeline[idx] = pipelineStage{shell: shell, stage: expandedStage, streams: e.stdio}
+		if shell.substitutions != substitutions {
+			pipeline[idx].status = shell.lastStatus
+		}
+	}
+
+	for idx := 0; idx < len(pipeline)-1; idx++ {
+		reader, writer, err := e.newPipe(pipeline[idx].stage, pipeline[idx+1].stage)
+		if err != nil {
+			closePipelineStages(pipeline)
+			return nil, fmt.Errorf("failed to create pipe: %w", err)
+		}
+
+		pipeline[idx].streams.stdout = writer
+		pipeline[idx].closers = append(pipeline[idx].closers, writer)
+
+		pipeline[idx+1].streams.stdin = reader
+		pipeline[idx+1].closers = append(pipeline[idx+1].closers, reader)
+	}
+
+	processes := make([]*jobs.Process, len(pipeline))
+	for idx, ps := range pipeline {
+		processes[idx] = ps.shell.startStage(ps, job, final && idx == len(pipeline)-1)
+	}
+
+	return processes, nil
+}
+
+// expandStage returns a copy of the stage with its tokens and redirection targets expanded
+func (e *Executor) expandStage(stage types.PipelineStage) (types.PipelineStage, error) {
+	var err error
+
+	if stage.Tokens, err = e.expandTokens(stage.Tokens); err != nil {
+		return stage, err
+	}
+
+	if stage.Arithmetic != "" {
+		if stage.Arithmetic, err = e.expandUnsplitWord(stage.Arithmetic); err != nil {
+			return stage, err
+		}
+
+		if strings.TrimSpace(stage.Arithmetic) == "" {
+			stage.Arithmetic = "0" // The expression was only made of empty expansions
+		}
+	}
+
+	if len(stage.Assignments) > 0 {
+		assignments := make([]types.Assignment, len(stage.Assignments))
+		for idx, a := range stage.Assignments {
+			if a.Value, err = e.expandUnsplitWord(a.Value); err != nil {
+				return stage, err
+			}
+
+			// Without a command, the assignments set shell variables right away, so each one sees the previous ones
+			if len(stage.Tokens) == 0 && stage.Arithmetic == "" && stage.Conditional == nil {
+				if err := e.setVariable(a.Name, a.Value); err != nil {
+					return stage, err
+				}
+			}
+
+			assignments[idx] = a
+		}
+		stage.Assignments = assignments
+	}
+
+	redirections := make([]types.Redirection, len(stage.Redirections))
+	for idx, r := range stage.Redirections {
+		switch r.Operator {
+		case types.RedirectHereDoc, types.RedirectHereDocTab:
+			// Quoting any part of the delimiter disables the expansion of the body
+			if !strings.ContainsAny(r.Target, `'"\`) {
+				r.Document, err = e.expandHereDoc(r.Document)
+			}
+		case types.RedirectHereString:
+			r.Target, err = e.expandUnsplitWord(r.Target)
+		default:
+			r.Target, err = e.expandSingleWord(r.Target)
+		}
+
+		if err != nil {
+			return stage, err
+		}
+
+		redirections[idx] = r
+	}
+	stage.Redirections = redirections
+
+	return stage, nil
+}
+
+// startStage starts a single stage of a pipeline as a member of the job
+// Builtins run in a goroutine while binaries are started as child processes
+// The redirections of the stage are applied first, even if there is no command to run (e.g. `> file`)
+func (e *Executor) startStage(ps pipelineStage, job *jobs.Job, final bool) *jobs.Process {
+	s, files, err := e.applyRedirections(ps.stage.Redirections, ps.streams)
+	if err != nil {
+		closeAll(ps.closers)
+		fmt.Fprintf(ps.streams.stderr, "gosh: %v\n", err)
+		return e.jobs.FinishedTask(job, final, statusFailure)
+	}
+
+	if ps.stage.Arithmetic != "" {
+		closeAll(ps.closers)
+		closeAll(files)
+		return e.jobs.FinishedTask(job, final, e.execArithmetic(ps.stage.Arithmetic, s))
+	}
+
+	if ps.stage.Conditional != nil {
+		closeAll(ps.closers)
+		closeAll(files)
+		return e.jobs.FinishedTask(job, final, e.execConditional(ps.stage.Conditional, s))
+	}
+
+	// In a pipeline or in the background, the commands running in the shell itself run in a subshell instead,
+	// alongside the other stages
+	if e.runsInShell(ps.stage) {
+		p := e.jobs.StartTask(job, final)
+		subshell := e.subshell(s)
+
+		go func() {
+			status := subshell.runInShell(ps.stage)
+			closeAll(ps.closers)
+			closeAll(files)
+			e.jobs.FinishTask(p, status)
+		}()
+
+		return p
+	}
+
+	if len(ps.stage.Tokens) == 0 {
+		closeAll(ps.closers)
+		closeAll(files)
+		return e.jobs.FinishedTask(job, final, ps.status)
+	}
+
+	// The exit builtin ends the whole shell, while in a subshell it only ends the subshell
+	if e.isSubshell && ps.stage.Tokens[0] == builtins.BuiltinExit {
+		closeAll(ps.closers)
+		closeAll(files)
+		return e.jobs.FinishedTask(job, final, e.exitSubshell(ps.stage.Tokens[1:], s))
+	}
+
+	if knownCmd, isKnownCmd := e.lookupBuiltin(ps.stage); isKnownCmd {
+		p := e.jobs.StartTask(job, final)
+
+		go func() {
+			status := statusFailure
+
+			restoreVariables, err := e.setTemporaryVariables(ps.stage.Assignments)
+			if err != nil {
+				fmt.Fprintf(s.stderr, "gosh: %v\n", err)
+			} else {
+				status = e.execBuiltin(knownCmd, ps.stage, s)
+				restoreVariables()
+			}
+
+			closeAll(ps.closers)
+			closeAll(files)
+			e.jobs.FinishTask(p, status)
+		}()
+
+		return p
+	}
+
+	cmd, err := e.prepareBinary(ps.stage, s)
+	if err != nil {
+		closeAll(ps.closers)
+		closeAll(files)
+		fmt.Fprintln(s.stderr, err.Error())
+
+		if errors.Is(err, errNotFound) {
+			return e.jobs.FinishedTask(job, final, statusNotFound)
+		}
+		return e.jobs.FinishedTask(job, final, statusFailure)
+	}
+
+	p, err := e.jobs.StartProcess(job, cmd, final)
+
+	// The child holds its own copies of the pipe ends and files, release ours so EOF propagates
+	closeAll(ps.closers)
+	closeAll(files)
+
+	if err != nil {
+		fmt.Fprintln(s.stderr, err.Error())
+		return e.jobs.FinishedTask(job, final, statusNotExecutable)
+	}
+
+	return p
+}
+
+// newPipe creates the pipe between two consecutive stages
+// Two builtins are connected through an in-memory pipe, while binaries always get an OS pipe
+// so they are wired to real files and can be reaped by the job table
+func (e *Executor) newPipe(current, next types.PipelineStage) (io.ReadCloser, io.WriteCloser, error) {
+	_, currentIsBuiltin := e.lookupBuiltin(current)
+	_, nextIsBuiltin := e.lookupBuiltin(next)
+
+	if currentIsBuiltin && nextIsBuiltin {
+		reader, writer := io.Pipe()
+		return reader, writer, nil
+	}
+
+	return os.Pipe()
+}
+
+// closePipelineStages closes all the pipe ends owned by the given stages
+func closePipelineStages(pipeline []pipelineStage) {
+	for _, ps := range pipeline {
+		closeAll(ps.closers)
+	}
+}
+
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		closer.Close()
+	}
+}
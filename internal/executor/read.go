@@ -0,0 +1,415 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/utils"
+)
+
+const (
+	// replyVariable is the variable set by read when it is given no names
+	replyVariable = "REPLY"
+
+	// statusReadTimeout is the exit status of read when it times out, as if it was killed by SIGALRM
+	statusReadTimeout = statusSignalOffset + int(syscall.SIGALRM)
+
+	runeEndOfInput = 4   // Ctrl+D, ending the input typed on the terminal
+	runeBackspace  = 8   // Ctrl+H
+	runeDelete     = 127 // Backspace
+)
+
+// Terminal is the terminal of an interactive shell, which the prompt reads from all along, so that read gets the characters
+// typed on it through the terminal rather than from stdin. Its input is not echoed, the readers echo it themselves
+type Terminal interface {
+	// ReadChar returns the next character typed, Enter being a newline. It fails with os.ErrDeadlineExceeded
+	// once the timeout elapsed, if it is not 0, and with types.ErrInterrupted on Ctrl+C
+	ReadChar(timeout time.Duration) (rune, error)
+	Echo(text string)
+}
+
+// readOptions are the options of read
+type readOptions struct {
+	raw        bool   // -r, backslashes do not escape the characters following them
+	prompt     string // -p, printed before reading from the terminal
+	silent     bool   // -s, the characters typed on the terminal are not echoed
+	timeout    time.Duration
+	hasTimeout bool   // -t, read fails if the input is not complete in time
+	count      int    // -n, read returns after that many characters, 0 reading up to the delimiter
+	delimiter  rune   // -d, the character ending the input instead of a newline
+	array      string // -a, the fields are assigned to the elements of an array
+	names      []string
+}
+
+// SetTerminal sets the terminal of an interactive shell, which read gets its input from when stdin is not redirected
+func (e *Executor) SetTerminal(terminal Terminal) {
+	e.terminal = terminal
+}
+
+// Read runs read, which reads a line of input and splits it into fields on the characters of IFS,
+// assigning them to the given variables, the last one getting the rest of the line, or to REPLY as is when there are none
+// Its exit status is 1 at the end of the input, even though the text read before it is assigned
+func (e *Executor) Read(args []string, stdio types.Stdio) int {
+	s := streams{stdin: stdio.Stdin, stdout: stdio.Stdout, stderr: stdio.Stderr}
+
+	opts, err := parseReadArgs(args)
+	if err != nil {
+		fmt.Fprintf(s.stderr, "%s: %v\n", builtins.BuiltinRead, err)
+		return statusUsage
+	}
+
+	for _, name := range append(opts.names, opts.array) {
+		if name != "" && !parser.IsValidName(name) {
+			fmt.Fprintf(s.stderr, "%s: `%s': not a valid identifier\n", builtins.BuiltinRead, name)
+			return statusFailure
+		}
+	}
+
+	fromTerminal := e.terminal != nil && s.stdin == io.Reader(os.Stdin)
+	if fromTerminal && opts.prompt != "" {
+		fmt.Fprint(s.stderr, opts.prompt)
+	}
+
+	var input *readInput
+	if fromTerminal {
+		input = &readInput{terminal: e.terminal, echo: !opts.silent}
+	} else {
+		input = &readInput{reader: s.stdin}
+	}
+
+	if opts.hasTimeout {
+		if opts.timeout == 0 {
+			return input.ready() // -t 0 only tells whether there is input to read
+		}
+		input.deadline = time.Now().Add(opts.timeout)
+	}
+
+	line, escaped, err := input.readLine(opts)
+	status := statusSuccess
+	switch {
+	case errors.Is(err, types.ErrInterrupted):
+		return statusSignalOffset + int(syscall.SIGINT)
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		status = statusReadTimeout
+	case errors.Is(err, io.EOF):
+		status = statusFailure
+	case err != nil:
+		fmt.Fprintf(s.stderr, "%s: read error: %v\n", builtins.BuiltinRead, err)
+		return statusFailure
+	}
+
+	if err := e.assignRead(opts, line, escaped); err != nil {
+		fmt.Fprintf(s.stderr, "%s: %v\n", builtins.BuiltinRead, err)
+		return statusFailure
+	}
+
+	return status
+}
+
+// assignRead assigns the line read to the variables given to read, to the elements of its array, or to REPLY
+func (e *Executor) assignRead(opts readOptions, line []rune, escaped []bool) error {
+	ifs, isSet := e.vars.Lookup("IFS")
+	if !isSet {
+		ifs = defaultIFS
+	}
+
+	switch {
+	case opts.array != "":
+		return e.vars.SetArray(opts.array, splitRead(line, escaped, ifs, 0))
+	case len(opts.names) == 0:
+		return e.setVariable(replyVariable, string(line))
+	}
+
+	fields := splitRead(line, escaped, ifs, len(opts.names))
+	for idx, name := range opts.names {
+		value := ""
+		if idx < len(fields) {
+			value = fields[idx]
+		}
+
+		if err := e.setVariable(name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitRead splits a line read into at most count fields, every one if count is 0, the last one holding the rest of the line
+// Blanks of IFS are trimmed around the fields, while any other character of IFS separates two fields, empty ones included,
+// as the expansions are split. Characters escaped with a backslash never separate fields
+func splitRead(line []rune, escaped []bool, ifs string, count int) []string {
+	isDelimiter := func(idx int) bool {
+		return !escaped[idx] && strings.ContainsRune(ifs, line[idx])
+	}
+	isBlank := func(idx int) bool {
+		return isDelimiter(idx) && strings.ContainsRune(defaultIFS, line[idx])
+	}
+
+	var fields []string
+
+	idx := 0
+	for idx < len(line) && isBlank(idx) {
+		idx++
+	}
+
+	for idx < len(line) {
+		if count > 0 && len(fields) == count-1 {
+			// The separator ending the line after a single remaining field is dropped along with it, e.g. the last : of 1:2:
+			end := idx
+			for end < len(line) && !isDelimiter(end) {
+				end++
+			}
+			if separatorEnd, _ := ifsSeparator(line, end, isDelimiter); separatorEnd == len(line) {
+				return append(fields, string(line[idx:end]))
+			}
+
+			end = len(line)
+			for end > idx && isBlank(end-1) {
+				end--
+			}
+			return append(fields, string(line[idx:end]))
+		}
+
+		start := idx
+		for idx < len(line) && !isDelimiter(idx) {
+			idx++
+		}
+		fields = append(fields, string(line[start:idx]))
+
+		idx, _ = ifsSeparator(line, idx, isDelimiter)
+	}
+
+	return fields
+}
+
+// readInput is the input of read, either the terminal, read through the prompt, or its stdin, read one character
+// at a time so that the input following the line is left for the next commands
+type readInput struct {
+	terminal Terminal
+	echo     bool // whether the characters typed on the terminal are echoed
+
+	reader   io.Reader
+	deadline time.Time // zero without timeout
+}
+
+// readLine reads up to the delimiter, or up to the given number of characters, returning the characters read
+// along with whether each of them was escaped by a backslash, which is removed along with escaped newlines
+// It returns the characters read so far with the error ending the input, such as io.EOF or os.ErrDeadlineExceeded
+func (in *readInput) readLine(opts readOptions) ([]rune, []bool, error) {
+	var line []rune
+	var escaped []bool
+	escaping := false
+
+	for opts.count == 0 || len(line) < opts.count {
+		char, err := in.next()
+		if err != nil {
+			return line, escaped, err
+		}
+
+		// The terminal is not in canonical mode, so the line is edited here
+		if in.terminal != nil {
+			switch char {
+			case runeEndOfInput:
+				if len(line) == 0 && !escaping {
+					return line, escaped, io.EOF
+				}
+				continue
+			case runeDelete, runeBackspace:
+				if len(line) > 0 {
+					line, escaped = line[:len(line)-1], escaped[:len(escaped)-1]
+					in.write("\b \b")
+				}
+				continue
+			}
+		}
+
+		isEscaped := escaping
+		escaping = false
+
+		switch {
+		case isEscaped && char == '\n':
+			in.write("\n")
+			continue // An escaped newline continues the line
+		case isEscaped:
+		case char == '\\' && !opts.raw:
+			escaping = true
+			in.write(string(char))
+			continue
+		case char == opts.delimiter:
+			if char == '\n' {
+				in.write("\n")
+			}
+			return line, escaped, nil
+		}
+
+		line = append(line, char)
+		escaped = append(escaped, isEscaped)
+		in.write(string(char))
+	}
+
+	return line, escaped, nil
+}
+
+// next reads the next character of the input, waiting for it until the deadline if there is one
+func (in *readInput) next() (rune, error) {
+	var timeout time.Duration
+	if !in.deadline.IsZero() {
+		if timeout = time.Until(in.deadline); timeout <= 0 {
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+
+	if in.terminal != nil {
+		return in.terminal.ReadChar(timeout)
+	}
+
+	if !in.deadline.IsZero() {
+		if ready, err := in.poll(timeout); err == nil && !ready {
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+
+	return readRune(in.reader)
+}
+
+// ready returns the exit status of read -t 0, which succeeds if the input can be read right away, without reading it
+func (in *readInput) ready() int {
+	if in.terminal == nil {
+		if ready, err := in.poll(0); err == nil && ready {
+			return statusSuccess
+		}
+	}
+
+	return statusFailure
+}
+
+// poll waits for input to be available until the timeout, a reader which is not a file, such as a here-string, always having some
+func (in *readInput) poll(timeout time.Duration) (bool, error) {
+	file, isFile := in.reader.(*os.File)
+	if !isFile {
+		return true, nil
+	}
+
+	return utils.WaitForInput(file, timeout)
+}
+
+// write echoes text to the terminal, unless the input is not the terminal or read is silent
+func (in *readInput) write(text string) {
+	if in.terminal != nil && in.echo {
+		in.terminal.Echo(text)
+	}
+}
+
+// readRune reads a single UTF-8 character, one byte at a time
+func readRune(r io.Reader) (rune, error) {
+	buf := make([]byte, utf8.UTFMax)
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return 0, err
+	}
+
+	size := 1
+	switch {
+	case buf[0]&0xE0 == 0xC0:
+		size = 2
+	case buf[0]&0xF0 == 0xE0:
+		size = 3
+	case buf[0]&0xF8 == 0xF0:
+		size = 4
+	}
+
+	if size > 1 {
+		if _, err := io.ReadFull(r, buf[1:size]); err != nil {
+			return utf8.RuneError, nil
+		}
+	}
+
+	char, _ := utf8.DecodeRune(buf[:size])
+	return char, nil
+}
+
+// parseReadArgs parses the options of read, which may be grouped (e.g. -rp), the options taking a value
+// getting the rest of their argument or the next one, followed by the names of the variables
+func parseReadArgs(args []string) (readOptions, error) {
+	opts := readOptions{delimiter: '\n'}
+
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		arg := args[0]
+		args = args[1:]
+
+		if arg == "--" {
+			break
+		}
+
+		for idx := 1; idx < len(arg); idx++ {
+			flag := arg[idx]
+
+			switch flag {
+			case 'r':
+				opts.raw = true
+				continue
+			case 's':
+				opts.silent = true
+				continue
+			case 'p', 't', 'n', 'd', 'a':
+			default:
+				return opts, fmt.Errorf("-%c: invalid option", flag)
+			}
+
+			value := arg[idx+1:]
+			if value == "" {
+				if len(args) == 0 {
+					return opts, fmt.Errorf("-%c: option requires an argument", flag)
+				}
+				value, args = args[0], args[1:]
+			}
+
+			if err := opts.set(flag, value); err != nil {
+				return opts, err
+			}
+			break
+		}
+	}
+
+	opts.names = args
+	return opts, nil
+}
+
+// set sets an option of read taking a value
+func (opts *readOptions) set(flag byte, value string) error {
+	switch flag {
+	case 'p':
+		opts.prompt = value
+	case 't':
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 {
+			return fmt.Errorf("%s: invalid timeout specification", value)
+		}
+		opts.timeout, opts.hasTimeout = time.Duration(seconds*float64(time.Second)), true
+	case 'n':
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return fmt.Errorf("%s: invalid number", value)
+		}
+		opts.count = count
+	case 'd':
+		opts.delimiter, _ = utf8.DecodeRuneInString(value)
+		if value == "" {
+			opts.delimiter = 0 // An empty delimiter reads up to a NUL character
+		}
+	case 'a':
+		opts.array = value
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/utils"
+)
+
+var errBadFd = errors.New("bad file descriptor")
+
+// fdTable maps the file descriptors of a command to the streams they refer to
+// A stream is an *os.File, an end of an in-memory pipe or closedFd
+type fdTable map[int]any
+
+// closedFd stands for a file descriptor closed with a redirection such as >&-
+type closedFd struct{}
+
+func (closedFd) Read([]byte) (int, error) {
+	return 0, errBadFd
+}
+
+func (closedFd) Write([]byte) (int, error) {
+	return 0, errBadFd
+}
+
+// applyRedirections applies the redirections of a stage, in order, on top of the streams it is wired to
+// It returns the resulting streams and the files opened along the way, to be closed once the command is done with them
+func (e *Executor) applyRedirections(redirections []types.Redirection, s streams) (streams, []io.Closer, error) {
+	if len(redirections) == 0 {
+		return s, nil, nil
+	}
+
+	fds := s.fdTable()
+
+	var files []io.Closer
+	for _, r := range redirections {
+		file, err := e.applyRedirection(r, fds)
+		if err != nil {
+			closeAll(files)
+			return s, nil, err
+		}
+
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+
+	redirected, err := fds.streams()
+	if err != nil {
+		closeAll(files)
+		return s, nil, err
+	}
+
+	return redirected, files, nil
+}
+
+// applyRedirection applies a single redirection to the file descriptors of a command,
+// returning the file it opened, if any
+func (e *Executor) applyRedirection(r types.Redirection, fds fdTable) (*os.File, error) {
+	switch r.Operator {
+	case types.RedirectDupInput, types.RedirectDupOutput:
+		// >&word with a word which is not a file descriptor redirects both stdout and stderr, like &>word
+		if _, err := strconv.Atoi(r.Target); err != nil && r.Operator == types.RedirectDupOutput && r.Fd == types.Stdout && r.Target != "-" {
+			return e.applyRedirection(types.Redirection{Operator: types.RedirectOutputAll, Target: r.Target}, fds)
+		}
+
+		return nil, duplicateFd(r, fds)
+	case types.RedirectHereString, types.RedirectHereDoc, types.RedirectHereDocTab:
+		content := r.Document
+		if r.Operator == types.RedirectHereString {
+			content = r.Target + "\n"
+		}
+
+		file, err := newHereFile(content)
+		if err != nil {
+			return nil, err
+		}
+
+		fds[r.Fd] = file
+		return file, nil
+	}
+
+	file, err := e.openRedirectFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fds[r.Fd] = file
+	if r.Operator == types.RedirectOutputAll || r.Operator == types.RedirectAppendAll {
+		fds[types.Stderr] = file
+	}
+
+	return file, nil
+}
+
+// duplicateFd makes a file descriptor refer to the same stream as another one (e.g. 2>&1), or closes it (e.g. 2>&-)
+func duplicateFd(r types.Redirection, fds fdTable) error {
+	if r.Target == "-" {
+		fds[r.Fd] = closedFd{}
+		return nil
+	}
+
+	source, err := strconv.Atoi(r.Target)
+	if err != nil {
+		return fmt.Errorf("%s: ambiguous redirect", r.Target)
+	}
+
+	stream, isOpen := fds[source]
+	if _, isClosed := stream.(closedFd); !isOpen || isClosed {
+		return fmt.Errorf("%d: %w", source, errBadFd)
+	}
+
+	fds[r.Fd] = stream
+	return nil
+}
+
+// openRedirectFile opens the file targeted by a redirection, in the mode required by its operator
+// With the noclobber option set, > and &> refuse to overwrite an existing regular file while >| always does
+func (e *Executor) openRedirectFile(r types.Redirection) (*os.File, error) {
+	var file *os.File
+	var err error
+
+	path := e.ResolvePath(r.Target)
+
+	switch r.Operator {
+	case types.RedirectInput:
+		file, err = os.Open(path)
+	case types.RedirectReadWrite:
+		file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	case types.RedirectAppend, types.RedirectAppendAll:
+		file, err = utils.OpenFileForStdout(path, false)
+	case types.RedirectOutput, types.RedirectOutputAll:
+		if e.options != nil && e.options.Noclobber {
+			if info, statErr := os.Stat(path); statErr == nil && info.Mode().IsRegular() {
+				return nil, fmt.Errorf("%s: cannot overwrite existing file", r.Target)
+			}
+		}
+		fallthrough
+	case types.RedirectClobber:
+		file, err = utils.OpenFileForStdout(path, true)
+	default:
+		return nil, fmt.Errorf("unsupported redirection operator %s", r.Operator)
+	}
+
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return nil, fmt.Errorf("%s: %w", r.Target, pathErr.Err)
+	}
+
+	return file, err
+}
+
+// newHereFile returns a file open for reading and holding the given content, used as the input of a here-string or here-document
+// The file is removed right away, so it only lives as long as it is open
+func newHereFile(content string) (*os.File, error) {
+	file, err := os.CreateTemp("", "gosh-here-")
+	if err != nil {
+		return nil, err
+	}
+
+	os.Remove(file.Name())
+
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// fdTable returns the file descriptors of the streams
+func (s streams) fdTable() fdTable {
+	fds := fdTable{
+		types.Stdin:  s.stdin,
+		types.Stdout: s.stdout,
+		types.Stderr: s.stderr,
+	}
+
+	for idx, file := range s.extraFiles {
+		if file != nil {
+			fds[types.Stderr+1+idx] = file
+		}
+	}
+
+	return fds
+}
+
+// streams returns the streams matching the file descriptors
+// Only files can be passed to a command on the file descriptors following stderr, the other ones are left closed
+func (fds fdTable) streams() (streams, error) {
+	var s streams
+	var isValid bool
+
+	if s.stdin, isValid = fds[types.Stdin].(io.Reader); !isValid {
+		return s, fmt.Errorf("%d: %w", types.Stdin, errBadFd)
+	}
+	if s.stdout, isValid = fds[types.Stdout].(io.Writer); !isValid {
+		return s, fmt.Errorf("%d: %w", types.Stdout, errBadFd)
+	}
+	if s.stderr, isValid = fds[types.Stderr].(io.Writer); !isValid {
+		return s, fmt.Errorf("%d: %w", types.Stderr, errBadFd)
+	}
+
+	for fd, stream := range fds {
+		file, isFile := stream.(*os.File)
+		if fd <= types.Stderr || !isFile {
+			continue
+		}
+
+		idx := fd - types.Stderr - 1
+		for len(s.extraFiles) <= idx {
+			s.extraFiles = append(s.extraFiles, nil)
+		}
+		s.extraFiles[idx] = file
+	}
+
+	return s, nil
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/traps"
+	"github.com/SebastianRichiteanu/Gosh/internal/utils"
+)
+
+// SourceFile runs the commands of a file in the shell itself, such as the goshrc at startup
+// The config is updated afterwards, as the file may have changed the variables it is read from
+// An interactive shell goes on whatever failed in the file, e.g. a command with set -e
+func (e *Executor) SourceFile(filePath string) int {
+	status := e.Source(filePath, nil)
+	if e.interactive {
+		e.exited = false
+	}
+
+	return status
+}
+
+// Source runs the commands of a file in the shell itself, with the given arguments as positional parameters if there are any
+// It returns the exit status of the last command
+func (e *Executor) Source(filePath string, args []string) int {
+	status, err := e.source(filePath, args)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "%s: could not source file at path %s: %v\n", builtins.BuiltinSource, filePath, err)
+		return statusFailure
+	}
+
+	if e.cfg != nil {
+		if err := e.cfg.Update(); err != nil {
+			fmt.Fprintf(e.stdio.stderr, "failed to refresh config: %v\n", err)
+		}
+	}
+
+	return status
+}
+
+// source parses the whole file before running it, so a syntax error anywhere in the file prevents it from running
+func (e *Executor) source(filePath string, args []string) (int, error) {
+	expandedPath, err := utils.ExpandHomePath(filePath)
+	if err != nil {
+		return statusFailure, err
+	}
+
+	content, err := os.ReadFile(e.ResolvePath(expandedPath))
+	if err != nil {
+		return statusFailure, err
+	}
+
+	prompt, err := e.parser.Parse(string(content))
+	if err != nil {
+		return statusFailure, err
+	}
+
+	if len(args) > 0 {
+		positionalArgs := e.positionalArgs
+		e.positionalArgs = args
+		defer func() { e.positionalArgs = positionalArgs }()
+	}
+
+	// return leaves the file, the commands following the source command still running
+	e.callDepth++
+	defer func() {
+		e.RunTrap(traps.Return)
+
+		e.callDepth--
+		e.returning = false
+	}()
+
+	return e.Execute(prompt), nil
+}
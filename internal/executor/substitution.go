@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+)
+
+// expandSubstitution expands the command substitution at the beginning of the input, either $(...) or `...`,
+// writing the output of the command to the field builder
+// It returns the number of bytes consumed from the input
+func (e *Executor) expandSubstitution(input string, fb *fieldBuilder, quoted bool, ifs string) (int, error) {
+	length, err := parser.SubstitutionLength(input)
+	if err != nil {
+		return 0, err
+	}
+
+	var command string
+	if input[0] == '`' {
+		command = unescapeBackquoted(input[1 : length-1])
+	} else {
+		command = input[2 : length-1]
+	}
+
+	output, err := e.substituteCommand(command)
+	if err != nil {
+		return 0, err
+	}
+
+	if quoted {
+		fb.write(output)
+	} else {
+		fb.writeSplit(output, ifs)
+	}
+
+	return length, nil
+}
+
+// substituteCommand runs a command in a subshell and returns what it wrote to stdout, without the trailing newlines
+// The command is parsed and executed by the shell itself, so builtins and aliases can be used
+func (e *Executor) substituteCommand(command string) (string, error) {
+	prompt, err := e.parser.Parse(command)
+	if err != nil {
+		return "", err
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+
+	// The output is read while the command runs, so it never blocks on a full pipe
+	output := make(chan string, 1)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		output <- string(data)
+	}()
+
+	// Like in other shells, the exit status of the substitution is available as $? to the rest of the command
+	e.substitutions++
+	e.lastStatus = e.subshell(streams{stdin: e.stdio.stdin, stdout: writer, stderr: e.stdio.stderr}).Execute(prompt)
+	writer.Close()
+
+	return strings.TrimRight(<-output, "\n"), nil
+}
+
+// exitSubshell stops the subshell and returns its exit status, given as argument or the one of the last command
+func (e *Executor) exitSubshell(args []string, s streams) int {
+	e.exited = true
+
+	if len(args) == 0 {
+		return e.lastStatus
+	}
+
+	status, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Fprintf(s.stderr, "%s: %s: numeric argument required\n", builtins.BuiltinExit, args[0])
+		return 2
+	}
+
+	return status
+}
+
+// unescapeBackquoted removes the backslashes escaping $, ` and \ inside a backquoted command substitution
+func unescapeBackquoted(command string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(command); i++ {
+		if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("$`\\", command[i+1]) != -1 {
+			i++
+		}
+		sb.WriteByte(command[i])
+	}
+
+	return sb.String()
+}
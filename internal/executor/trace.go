@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// defaultTracePrefix is printed before the traced commands when PS4 is not set
+const defaultTracePrefix = "+ "
+
+// trace prints an expanded command to stderr right before it runs, with set -x, after the expanded value of PS4
+// Compound commands are not printed, the commands making them up are as they run
+func (e *Executor) trace(stage types.PipelineStage) {
+	if e.options == nil || !e.options.Xtrace {
+		return
+	}
+
+	var words []string
+	for _, a := range stage.Assignments {
+		words = append(words, a.Name+"="+quoteTraced(a.Value))
+	}
+
+	if stage.Arithmetic != "" {
+		words = append(words, "((", stage.Arithmetic, "))")
+	}
+
+	if stage.Conditional != nil {
+		words = append(words, "[[", strings.Join(stage.Conditional, " "), "]]")
+	}
+
+	for _, token := range stage.Tokens {
+		words = append(words, quoteTraced(token))
+	}
+
+	if len(words) == 0 {
+		return
+	}
+
+	prefix := defaultTracePrefix
+	if ps4, isSet := e.vars.Lookup("PS4"); isSet {
+		if expanded, err := e.expandUnsplitWord(ps4); err == nil {
+			prefix = expanded
+		}
+	}
+
+	fmt.Fprintf(e.stdio.stderr, "%s%s\n", prefix, strings.Join(words, " "))
+}
+
+// quoteTraced single-quotes a traced word if the shell would not read it back as is
+func quoteTraced(word string) string {
+	if word != "" && !strings.ContainsFunc(word, needsQuoting) {
+		return word
+	}
+
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+func needsQuoting(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+
+	return !strings.ContainsRune("_-./:=,+@%^", r)
+}
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+)
+
+// RunTrap runs the command trapped for a signal or a pseudo-signal, if there is one, leaving $? as it was
+// Subshells do not run the traps of the shell
+func (e *Executor) RunTrap(signal string) {
+	if e.traps == nil || e.runningTrap {
+		return
+	}
+
+	command, isTrapped := e.traps.Get(signal)
+	if !isTrapped || command == "" {
+		return
+	}
+
+	prompt, err := e.parser.Parse(command)
+	if err != nil {
+		fmt.Fprintf(e.stdio.stderr, "gosh: %s: %v\n", builtins.BuiltinTrap, err)
+		return
+	}
+
+	// The trapped command runs even if the shell or a function is leaving, as with the EXIT and RETURN traps
+	lastStatus, exited, returning := e.lastStatus, e.exited, e.returning
+	e.exited, e.returning = false, false
+	e.runningTrap = true
+
+	e.Execute(prompt)
+
+	e.runningTrap = false
+	e.lastStatus, e.exited, e.returning = lastStatus, exited || e.exited, returning
+}
+
+// RunPendingTraps runs the commands trapped for the signals received since the last call, in the order they were received
+func (e *Executor) RunPendingTraps() {
+	if e.traps == nil || e.runningTrap {
+		return
+	}
+
+	for _, signal := range e.traps.TakePending() {
+		e.RunTrap(signal)
+	}
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+	"github.com/SebastianRichiteanu/Gosh/internal/variables"
+)
+
+// setVariable sets the value of a shell variable
+func (e *Executor) setVariable(name, value string) error {
+	return e.vars.Set(name, value)
+}
+
+// setTemporaryVariables sets the variables assigned before a builtin for the time it runs, exported like for a binary
+// It returns the function restoring the variables as they were
+func (e *Executor) setTemporaryVariables(assignments []types.Assignment) (func(), error) {
+	type savedVariable struct {
+		name    string
+		v       variables.Variable
+		existed bool
+	}
+
+	var saved []savedVariable
+	restore := func() {
+		// Restored in reverse order, so a variable assigned twice gets back its state from before the first assignment
+		for idx := len(saved) - 1; idx >= 0; idx-- {
+			e.vars.Restore(saved[idx].name, saved[idx].v, saved[idx].existed)
+		}
+	}
+
+	exported := true
+	for _, a := range assignments {
+		v, existed := e.vars.Get(a.Name)
+		if err := e.vars.Set(a.Name, a.Value); err != nil {
+			restore()
+			return nil, err
+		}
+		saved = append(saved, savedVariable{name: a.Name, v: v, existed: existed})
+
+		e.vars.SetAttributes(a.Name, &exported, nil, nil)
+	}
+
+	return restore, nil
+}
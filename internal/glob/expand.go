@@ -0,0 +1,164 @@
+package glob
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// globstar is the path component matching any number of directories
+const globstar = "**"
+
+// Options changes the way patterns are expanded against the filesystem
+type Options struct {
+	Dotglob bool   // whether wildcards match names starting with a dot
+	Dir     string // the directory relative patterns are matched from, the working directory of the process if empty
+}
+
+// Expand returns the sorted paths matching a pattern, such as src/*.go or **/*_test.go
+// Every path component is matched separately, a ** component matching any number of nested directories
+// Names starting with a dot are only matched by a component starting with a dot, unless Dotglob is set
+func Expand(pattern string, opts Options) []string {
+	// A trailing slash only matches directories, and is kept in the results
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimRight(pattern, "/")
+
+	root := ""
+	if strings.HasPrefix(pattern, "/") {
+		root = "/"
+	}
+
+	var components []string
+	for _, component := range strings.Split(pattern, "/") {
+		if component != "" {
+			components = append(components, component)
+		}
+	}
+
+	e := expander{opts: opts}
+	e.expand(root, components)
+
+	matches := e.matches
+	if dirOnly {
+		matches = matches[:0]
+		for _, path := range e.matches {
+			if e.isDir(path) {
+				matches = append(matches, path+"/")
+			}
+		}
+	}
+
+	sort.Strings(matches)
+	return matches
+}
+
+type expander struct {
+	opts    Options
+	matches []string
+}
+
+// expand adds the paths matching the remaining components, starting from a directory
+func (e *expander) expand(dir string, components []string) {
+	if len(components) == 0 {
+		if dir != "" && dir != "/" {
+			e.matches = append(e.matches, dir)
+		}
+		return
+	}
+
+	component, rest := components[0], components[1:]
+
+	if component == globstar {
+		e.expandGlobstar(dir, rest)
+		return
+	}
+
+	if !HasMeta(component) {
+		path := joinPath(dir, Unescape(component))
+		if _, err := os.Lstat(e.fsPath(path)); err == nil {
+			e.expand(path, rest)
+		}
+		return
+	}
+
+	for _, entry := range e.readDir(dir) {
+		if !e.matchName(component, entry.Name()) {
+			continue
+		}
+
+		path := joinPath(dir, entry.Name())
+		if len(rest) == 0 || e.isDir(path) {
+			e.expand(path, rest)
+		}
+	}
+}
+
+// expandGlobstar adds the paths matching the remaining components in the directory and all the directories below it
+// Without remaining components, every file and directory below the directory matches
+func (e *expander) expandGlobstar(dir string, rest []string) {
+	if len(rest) > 0 {
+		e.expand(dir, rest)
+	}
+
+	for _, entry := range e.readDir(dir) {
+		if !e.matchName("*", entry.Name()) {
+			continue
+		}
+
+		path := joinPath(dir, entry.Name())
+		if len(rest) == 0 {
+			e.matches = append(e.matches, path)
+		}
+
+		// Symbolic links are not followed, so that a link to a parent directory does not loop
+		if entry.IsDir() {
+			e.expandGlobstar(path, rest)
+		}
+	}
+}
+
+// matchName matches a name against a path component, names starting with a dot being hidden from wildcards
+func (e *expander) matchName(component, name string) bool {
+	if strings.HasPrefix(name, ".") && !e.opts.Dotglob && !strings.HasPrefix(Unescape(component), ".") {
+		return false
+	}
+
+	return Match(component, name)
+}
+
+func (e *expander) readDir(dir string) []os.DirEntry {
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(e.fsPath(dir))
+	if err != nil {
+		return nil
+	}
+
+	return entries
+}
+
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	if strings.HasSuffix(dir, "/") {
+		return dir + name
+	}
+	return dir + "/" + name
+}
+
+func (e *expander) isDir(path string) bool {
+	info, err := os.Stat(e.fsPath(path))
+	return err == nil && info.IsDir()
+}
+
+// fsPath returns the path the filesystem is accessed with for a path matched, relative ones being found in Options.Dir
+func (e *expander) fsPath(path string) string {
+	if e.opts.Dir == "" || strings.HasPrefix(path, "/") {
+		return path
+	}
+
+	return e.opts.Dir + "/" + path
+}
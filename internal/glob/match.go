@@ -0,0 +1,198 @@
+package glob
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// characterClasses are the classes which can be used inside brackets, such as [[:digit:]]
+var characterClasses = map[string]func(rune) bool{
+	"alnum":  func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha":  unicode.IsLetter,
+	"blank":  func(r rune) bool { return r == ' ' || r == '\t' },
+	"cntrl":  unicode.IsControl,
+	"digit":  unicode.IsDigit,
+	"graph":  func(r rune) bool { return unicode.IsGraphic(r) && !unicode.IsSpace(r) },
+	"lower":  unicode.IsLower,
+	"print":  unicode.IsPrint,
+	"punct":  unicode.IsPunct,
+	"space":  unicode.IsSpace,
+	"upper":  unicode.IsUpper,
+	"word":   func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"xdigit": func(r rune) bool { return strings.ContainsRune("0123456789abcdefABCDEF", r) },
+}
+
+// HasMeta reports whether the pattern holds unescaped glob characters, so that it can match anything but itself
+func HasMeta(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '*', '?':
+			return true
+		case '[':
+			if _, _, isValid := matchBracket(pattern[i:], 0); isValid {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Unescape removes the backslashes escaping characters of a pattern, returning the text it matches
+func Unescape(pattern string) string {
+	if !strings.Contains(pattern, `\`) {
+		return pattern
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		sb.WriteByte(pattern[i])
+	}
+
+	return sb.String()
+}
+
+// Escape adds backslashes to the glob characters of a text, so that it only matches itself when used in a pattern
+func Escape(text string) string {
+	if !strings.ContainsAny(text, `*?[]\`) {
+		return text
+	}
+
+	var sb strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(`*?[]\`, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// Match reports whether the whole name matches the shell pattern
+// * matches any string, ? any single character, and [...] any of the characters it holds, such as
+// [abc], [a-z] or [[:digit:]], [!...] or [^...] matching the other ones. A backslash escapes the next character
+func Match(pattern, name string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+
+			// Try every possible length for the star, the shortest first
+			for idx := 0; idx <= len(name); {
+				if Match(pattern, name[idx:]) {
+					return true
+				}
+				if idx == len(name) {
+					break
+				}
+
+				_, size := utf8.DecodeRuneInString(name[idx:])
+				idx += size
+			}
+			return false
+		case '?':
+			if name == "" {
+				return false
+			}
+
+			_, size := utf8.DecodeRuneInString(name)
+			pattern, name = pattern[1:], name[size:]
+			continue
+		case '[':
+			if name == "" {
+				return false
+			}
+
+			r, size := utf8.DecodeRuneInString(name)
+			if isMatch, length, isValid := matchBracket(pattern, r); isValid {
+				if !isMatch {
+					return false
+				}
+
+				pattern, name = pattern[length:], name[size:]
+				continue
+			}
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+		}
+
+		// A literal character, which must be the next one of the name
+		r, size := utf8.DecodeRuneInString(pattern)
+		nameRune, nameSize := utf8.DecodeRuneInString(name)
+		if name == "" || r != nameRune {
+			return false
+		}
+
+		pattern, name = pattern[size:], name[nameSize:]
+	}
+
+	return name == ""
+}
+
+// matchBracket matches a character against the bracket expression at the beginning of the pattern
+// It returns whether the character matches, the length of the expression and whether it is valid,
+// an opening bracket without a closing one being an ordinary character
+func matchBracket(pattern string, r rune) (bool, int, bool) {
+	idx := 1
+
+	negate := false
+	if idx < len(pattern) && (pattern[idx] == '!' || pattern[idx] == '^') {
+		negate = true
+		idx++
+	}
+
+	isMatch := false
+	for first := true; idx < len(pattern); first = false {
+		// A closing bracket right after the opening one is part of the set
+		if pattern[idx] == ']' && !first {
+			return isMatch != negate, idx + 1, true
+		}
+
+		if strings.HasPrefix(pattern[idx:], "[:") {
+			if end := strings.Index(pattern[idx+2:], ":]"); end != -1 {
+				if class, isClass := characterClasses[pattern[idx+2:idx+2+end]]; isClass {
+					isMatch = isMatch || class(r)
+					idx += end + 4
+					continue
+				}
+			}
+		}
+
+		low, size := decodeBracketChar(pattern[idx:])
+		idx += size
+
+		high := low
+		if idx+1 < len(pattern) && pattern[idx] == '-' && pattern[idx+1] != ']' {
+			high, size = decodeBracketChar(pattern[idx+1:])
+			idx += size + 1
+		}
+
+		if low <= r && r <= high {
+			isMatch = true
+		}
+	}
+
+	return false, 0, false
+}
+
+// decodeBracketChar decodes a character of a bracket expression, which may be escaped with a backslash
+func decodeBracketChar(pattern string) (rune, int) {
+	if pattern[0] == '\\' && len(pattern) > 1 {
+		r, size := utf8.DecodeRuneInString(pattern[1:])
+		return r, size + 1
+	}
+
+	return utf8.DecodeRuneInString(pattern)
+}
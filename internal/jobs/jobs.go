@@ -0,0 +1,600 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// State is the state of a job or of one of its processes
+type State int
+
+const (
+	Running State = iota
+	Stopped
+	Done
+)
+
+// By convention the exit status of a process killed or stopped by a signal is 128 + signal number
+const statusSignalOffset = 128
+
+var (
+	errNoSuchJob     = errors.New("no such job")
+	errNoCurrent     = errors.New("no current job")
+	errJobStopped    = errors.New("job is stopped")
+	errJobTerminated = errors.New("job has terminated")
+)
+
+// Process is a member of a job, either an external process or a builtin running in a goroutine (with a Pid of 0)
+type Process struct {
+	Pid    int
+	state  State
+	status int
+
+	signal     syscall.Signal // the signal which killed the process, 0 if it exited
+	coreDumped bool
+}
+
+// Job is a pipeline (or a whole chain, when sent to the background) launched by the shell
+type Job struct {
+	ID      int
+	Pgid    int
+	Command string
+
+	// ownGroup is set for jobs that run in their own process group rather than the shell's one
+	ownGroup  bool
+	processes []*Process
+	final     *Process // the process whose exit status is the status of the job
+
+	foreground  bool           // the job starts in the foreground of an interactive shell, its process group owning the terminal
+	hasTerminal bool           // the terminal was handed to the job, the shell takes it back once the job is done or stopped
+	modes       *terminalModes // the modes of the terminal when the job was stopped, set again when it is resumed
+
+	registered bool  // whether the job is in the table and has an ID
+	reported   State // the last state the user was notified about
+}
+
+// Table keeps track of the jobs launched by the shell, reaping their processes and following their state
+type Table struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	jobs   []*Job // ordered by ID
+	recent []*Job // most recently used first, the first one is the current job (+) and the second the previous one (-)
+
+	shellPgid int
+	ttyFd     int // the controlling terminal of the shell, -1 if there is none
+
+	jobControl bool           // foreground jobs run in their own process group, which owns the terminal while they run
+	jobModes   *terminalModes // the modes of the terminal when the shell started, which the jobs start with
+	shellModes *terminalModes // the modes of the terminal for the shell, saved while a job owns the terminal
+	foreground *Job           // the job the shell waits for in the foreground, nil if there is none
+}
+
+// NewTable creates an empty job table, with job control for an interactive shell with a terminal
+// It catches SIGTSTP so Ctrl+Z only suspends the foreground job and never the shell itself
+func NewTable(jobControl bool) *Table {
+	t := Table{
+		shellPgid: shellProcessGroup(),
+		ttyFd:     terminalFd(),
+	}
+	t.cond = sync.NewCond(&t.mu)
+
+	t.jobControl = jobControl && t.ttyFd >= 0
+	t.jobModes = t.getModes()
+
+	catchStopSignal()
+
+	return &t
+}
+
+// NewJob creates a job which is not yet part of the table
+// Background jobs get their own process group, so they do not receive the signals sent from the terminal
+func (t *Table) NewJob(command string, background bool) *Job {
+	return &Job{
+		Command:  command,
+		ownGroup: background,
+	}
+}
+
+// NewForegroundJob creates a job run in the foreground, which is not yet part of the table
+// With job control, it gets its own process group, which is handed the terminal while the job runs
+// so that the signals sent from the terminal (e.g. by Ctrl+C) only reach the job
+func (t *Table) NewForegroundJob(command string) *Job {
+	return &Job{
+		Command:    command,
+		ownGroup:   t.jobControl,
+		foreground: t.jobControl,
+	}
+}
+
+// Add registers a job in the table, making it the current job, and returns its ID
+func (t *Table) Add(job *Job) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.add(job)
+	return job.ID
+}
+
+func (t *Table) add(job *Job) {
+	if job.registered {
+		t.touch(job)
+		return
+	}
+
+	job.ID = 1
+	if len(t.jobs) > 0 {
+		job.ID = t.jobs[len(t.jobs)-1].ID + 1
+	}
+
+	job.registered = true
+	job.reported = job.state()
+	t.jobs = append(t.jobs, job)
+	t.touch(job)
+}
+
+// touch makes the job the current one
+func (t *Table) touch(job *Job) {
+	t.recent = slices.DeleteFunc(t.recent, func(j *Job) bool { return j == job })
+	t.recent = append([]*Job{job}, t.recent...)
+}
+
+func (t *Table) remove(job *Job) {
+	job.registered = false
+	t.jobs = slices.DeleteFunc(t.jobs, func(j *Job) bool { return j == job })
+	t.recent = slices.DeleteFunc(t.recent, func(j *Job) bool { return j == job })
+}
+
+// StartTask adds a builtin running in a goroutine to the job, FinishTask must be called once it returns
+func (t *Table) StartTask(job *Job, final bool) *Process {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return job.addProcess(0, final)
+}
+
+// FinishTask marks a builtin started with StartTask as done
+func (t *Table) FinishTask(p *Process, status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p.state = Done
+	p.status = status
+	t.cond.Broadcast()
+}
+
+// FinishedTask adds an already finished member to the job, used for commands that could not be started
+func (t *Table) FinishedTask(job *Job, final bool, status int) *Process {
+	p := t.StartTask(job, final)
+	t.FinishTask(p, status)
+	return p
+}
+
+// WaitProcesses waits for the given processes to finish and returns the exit status of the last one
+func (t *Table) WaitProcesses(processes []*Process) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range processes {
+		for p.state != Done {
+			t.cond.Wait()
+		}
+	}
+
+	if len(processes) == 0 {
+		return 0
+	}
+
+	return processes[len(processes)-1].status
+}
+
+// PipefailStatus returns the exit status of the last of the given processes which failed, 0 if they all succeeded
+// It is the exit status of a pipeline with set -o pipefail, once its processes are done
+func (t *Table) PipefailStatus(processes []*Process) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for idx := len(processes) - 1; idx >= 0; idx-- {
+		if processes[idx].status != 0 {
+			return processes[idx].status
+		}
+	}
+
+	return 0
+}
+
+// WaitForeground waits for a job running in the foreground to either finish or be stopped (e.g. by Ctrl+Z)
+// A stopped job is added to the table so it can be resumed later. It returns the exit status of the job
+// and whether it was stopped. The shell takes back the terminal the job owned, and reports the job if a signal killed it
+func (t *Table) WaitForeground(job *Job) (int, bool) {
+	status, stopped, _ := t.waitForeground(context.Background(), job)
+	return status, stopped
+}
+
+// waitForeground waits for a job running in the foreground like WaitForeground does, giving up once the context is cancelled
+// The job then goes on in the background, the shell taking back the terminal
+func (t *Table) waitForeground(ctx context.Context, job *Job) (int, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.wakeOnCancel(ctx)()
+
+	t.foreground = job
+	defer func() { t.foreground = nil }()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			t.takeTerminal(job)
+			t.add(job)
+			return statusSignalOffset + int(syscall.SIGINT), false, err
+		}
+
+		switch job.state() {
+		case Done:
+			t.takeTerminal(job)
+			if job.registered {
+				t.remove(job)
+			}
+			reportSignal(job)
+			return job.status(), false, nil
+		case Stopped:
+			t.takeTerminal(job)
+			t.add(job)
+			job.reported = Stopped
+			fmt.Fprintf(os.Stderr, "\n%s\n", t.format(job, false))
+			return job.status(), true, nil
+		}
+
+		t.cond.Wait()
+	}
+}
+
+// Interrupted checks whether the final process of a job was killed by SIGINT, as when Ctrl+C is pressed while it runs
+func (t *Table) Interrupted(job *Job) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return job.final != nil && job.final.signal == syscall.SIGINT
+}
+
+// Foreground resumes a job in the foreground, handing it the terminal, and waits for it until the context is cancelled
+func (t *Table) Foreground(ctx context.Context, job *Job, w io.Writer) (int, error) {
+	t.mu.Lock()
+	if job.state() == Done {
+		t.remove(job)
+		t.mu.Unlock()
+		return 1, errJobTerminated
+	}
+	t.touch(job)
+	t.mu.Unlock()
+
+	fmt.Fprintln(w, job.Command)
+
+	if job.ownGroup && job.Pgid != 0 {
+		t.mu.Lock()
+		t.giveTerminal(job)
+		err := t.setTerminalOwner(job.Pgid)
+		t.mu.Unlock()
+
+		if err != nil {
+			t.mu.Lock()
+			t.takeTerminal(job)
+			t.mu.Unlock()
+			return 1, err
+		}
+	}
+
+	if err := t.resume(job); err != nil {
+		t.mu.Lock()
+		t.takeTerminal(job)
+		t.mu.Unlock()
+		return 1, err
+	}
+
+	status, _, err := t.waitForeground(ctx, job)
+	return status, err
+}
+
+// Background resumes a stopped job in the background
+func (t *Table) Background(job *Job) (string, error) {
+	t.mu.Lock()
+	state := job.state()
+	t.mu.Unlock()
+
+	switch state {
+	case Done:
+		return "", errJobTerminated
+	case Running:
+		return "", fmt.Errorf("job %d already in background", job.ID)
+	}
+
+	if err := t.resume(job); err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.touch(job)
+	job.reported = Running
+
+	return fmt.Sprintf("[%d]+ %s &\n", job.ID, job.Command), nil
+}
+
+// Wait waits for a job to finish and removes it from the table, returning its exit status
+// It gives up once the context is cancelled, e.g. by Ctrl+C, the job going on in the background
+func (t *Table) Wait(ctx context.Context, job *Job) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.wakeOnCancel(ctx)()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return statusSignalOffset + int(syscall.SIGINT), err
+		}
+
+		switch job.state() {
+		case Done:
+			t.remove(job)
+			return job.status(), nil
+		case Stopped:
+			return job.status(), errJobStopped
+		}
+
+		t.cond.Wait()
+	}
+}
+
+// WaitAll waits for all the running jobs to finish, unless the context is cancelled first
+func (t *Table) WaitAll(ctx context.Context) error {
+	t.mu.Lock()
+	jobs := slices.Clone(t.jobs)
+	t.mu.Unlock()
+
+	for _, job := range jobs {
+		t.Wait(ctx, job)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wakeOnCancel wakes up the waits on the table once the context is cancelled, so that they give up
+// It returns the function stopping it, to call once the wait is over
+func (t *Table) wakeOnCancel(ctx context.Context) func() bool {
+	return context.AfterFunc(ctx, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		t.cond.Broadcast()
+	})
+}
+
+// Disown removes a job from the table without touching its processes
+func (t *Table) Disown(job *Job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.remove(job)
+}
+
+// Jobs returns the jobs currently in the table
+func (t *Table) Jobs() []*Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.Clone(t.jobs)
+}
+
+// SignalForeground sends a signal to the job the shell waits for in the foreground, if it runs in its own process group
+// The terminal sends the signals typed on it (e.g. Ctrl+C) to that group only, the ones the shell receives are passed on to the job
+// It returns whether there was such a job
+func (t *Table) SignalForeground(sig syscall.Signal) bool {
+	t.mu.Lock()
+	job := t.foreground
+	t.mu.Unlock()
+
+	if job == nil || !job.ownGroup || job.Pgid == 0 {
+		return false
+	}
+
+	return t.signal(job, sig) == nil
+}
+
+// Format returns the line describing a job, as printed by the jobs builtin
+func (t *Table) Format(job *Job, withPids bool) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.format(job, withPids)
+}
+
+// Pids returns the process IDs of a job
+func (t *Table) Pids(job *Job) []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var pids []int
+	for _, p := range job.processes {
+		if p.Pid != 0 {
+			pids = append(pids, p.Pid)
+		}
+	}
+	return pids
+}
+
+// Notify reports the jobs that finished or were stopped since the last notification,
+// removing the finished ones from the table
+func (t *Table) Notify(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, job := range slices.Clone(t.jobs) {
+		state := job.state()
+		if state == job.reported {
+			continue
+		}
+
+		fmt.Fprintln(w, t.format(job, false))
+		job.reported = state
+
+		if state == Done {
+			t.remove(job)
+		}
+	}
+}
+
+// Find returns the job matching a job spec: %n, %%, %+, %-, %prefix or %?substring
+// An empty spec refers to the current job
+func (t *Table) Find(spec string) (*Job, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spec = strings.TrimPrefix(spec, "%")
+
+	switch spec {
+	case "", "%", "+":
+		if len(t.recent) == 0 {
+			return nil, errNoCurrent
+		}
+		return t.recent[0], nil
+	case "-":
+		if len(t.recent) < 2 {
+			return nil, errNoSuchJob
+		}
+		return t.recent[1], nil
+	}
+
+	if id, err := strconv.Atoi(spec); err == nil {
+		for _, job := range t.jobs {
+			if job.ID == id {
+				return job, nil
+			}
+		}
+		return nil, errNoSuchJob
+	}
+
+	var match *Job
+	for _, job := range t.jobs {
+		var found bool
+		if substring, isSubstring := strings.CutPrefix(spec, "?"); isSubstring {
+			found = strings.Contains(job.Command, substring)
+		} else {
+			found = strings.HasPrefix(job.Command, spec)
+		}
+
+		if !found {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("%s: ambiguous job spec", spec)
+		}
+		match = job
+	}
+
+	if match == nil {
+		return nil, errNoSuchJob
+	}
+
+	return match, nil
+}
+
+// FindByPid returns the job one of whose processes has the given pid
+func (t *Table) FindByPid(pid int) (*Job, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, job := range t.jobs {
+		for _, p := range job.processes {
+			if p.Pid == pid {
+				return job, nil
+			}
+		}
+	}
+
+	return nil, errNoSuchJob
+}
+
+// format returns the line describing a job, e.g. `[1]+  Running                 sleep 10 &`
+func (t *Table) format(job *Job, withPids bool) string {
+	marker := " "
+	if len(t.recent) > 0 && t.recent[0] == job {
+		marker = "+"
+	} else if len(t.recent) > 1 && t.recent[1] == job {
+		marker = "-"
+	}
+
+	command := job.Command
+	state := job.state()
+
+	var description string
+	switch state {
+	case Running:
+		description = "Running"
+		command += " &"
+	case Stopped:
+		description = "Stopped"
+	case Done:
+		description = "Done"
+		if job.final != nil && job.final.signal != 0 {
+			description = describeSignal(job.final)
+		} else if status := job.status(); status != 0 {
+			description = fmt.Sprintf("Exit %d", status)
+		}
+	}
+
+	prefix := fmt.Sprintf("[%d]%s ", job.ID, marker)
+	if withPids {
+		prefix += fmt.Sprintf("%d ", job.Pgid)
+	}
+
+	return fmt.Sprintf("%s %-24s%s", prefix, description, command)
+}
+
+func (job *Job) addProcess(pid int, final bool) *Process {
+	p := &Process{Pid: pid}
+	job.processes = append(job.processes, p)
+	if final {
+		job.final = p
+	}
+	return p
+}
+
+// state returns Done once all the processes are done, Stopped if any of them is stopped and Running otherwise
+func (job *Job) state() State {
+	state := Done
+	for _, p := range job.processes {
+		switch p.state {
+		case Stopped:
+			return Stopped
+		case Running:
+			state = Running
+		}
+	}
+	return state
+}
+
+// status returns the exit status of the job, which is the status of its final process,
+// or the status of the stop signal if the job is stopped
+func (job *Job) status() int {
+	for _, p := range job.processes {
+		if p.state == Stopped {
+			return p.status
+		}
+	}
+
+	if job.final == nil {
+		return 0
+	}
+
+	return job.final.status
+}
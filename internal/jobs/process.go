@@ -0,0 +1,49 @@
+package jobs
+
+import "os/exec"
+
+// StartProcess starts an external command as a member of the job and follows its state until it exits
+// Processes of jobs with their own process group join the group of the job, or create it if it does not exist yet
+// The process creating the group of a foreground job makes it the foreground process group of the terminal
+// before running the command, so that the command never finds itself reading from the terminal in the background
+func (t *Table) StartProcess(job *Job, cmd *exec.Cmd, final bool) (*Process, error) {
+	t.mu.Lock()
+	newGroup := job.ownGroup && t.setProcessGroup(job, cmd)
+	t.mu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		// No other process of the job is running, the terminal is left to a group which does not exist
+		t.mu.Lock()
+		if !job.hasRunningProcess() {
+			t.takeTerminal(job)
+		}
+		t.mu.Unlock()
+
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pid := cmd.Process.Pid
+	if newGroup {
+		job.Pgid = pid
+	} else if !job.ownGroup {
+		job.Pgid = t.shellPgid
+	}
+
+	p := job.addProcess(pid, final)
+	go t.reap(p, cmd)
+
+	return p, nil
+}
+
+// hasRunningProcess checks if any external process of the job did not exit yet
+func (job *Job) hasRunningProcess() bool {
+	for _, p := range job.processes {
+		if p.Pid != 0 && p.state != Done {
+			return true
+		}
+	}
+	return false
+}
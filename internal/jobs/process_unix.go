@@ -0,0 +1,128 @@
+//go:build unix
+
+package jobs
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// shellProcessGroup returns the process group of the shell, which the jobs without one of their own share
+func shellProcessGroup() int {
+	return syscall.Getpgrp()
+}
+
+// catchStopSignal catches SIGTSTP, so that Ctrl+Z only suspends the foreground job and never the shell itself
+// Catching (rather than ignoring) the signal keeps its default behaviour for the children
+func catchStopSignal() {
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGTSTP)
+}
+
+// setProcessGroup makes a process of a job with its own process group join the group of the job,
+// or create it if it does not exist yet, which it tells
+func (t *Table) setProcessGroup(job *Job, cmd *exec.Cmd) bool {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+
+	// The previous process group is gone once all its members exited, a new one is created in that case
+	cmd.SysProcAttr.Pgid = 0
+	if job.hasRunningProcess() {
+		cmd.SysProcAttr.Pgid = job.Pgid
+	}
+
+	if job.foreground && cmd.SysProcAttr.Pgid == 0 && t.ttyFd >= 0 {
+		t.giveTerminal(job)
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = t.ttyFd
+	}
+
+	return cmd.SysProcAttr.Pgid == 0
+}
+
+// reap waits for the state changes of a process until it exits
+// The process is reaped by the table rather than by cmd.Wait, which cannot report stopped processes
+func (t *Table) reap(p *Process, cmd *exec.Cmd) {
+	cmd.Process.Release()
+
+	for {
+		var ws syscall.WaitStatus
+		_, err := syscall.Wait4(p.Pid, &ws, waitOptions, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+
+		t.mu.Lock()
+
+		switch {
+		case err != nil:
+			p.state = Done
+			p.status = 1
+		case ws.Exited():
+			p.state = Done
+			p.status = ws.ExitStatus()
+		case ws.Signaled():
+			p.state = Done
+			p.status = statusSignalOffset + int(ws.Signal())
+			p.signal = ws.Signal()
+			p.coreDumped = ws.CoreDump()
+		case ws.Stopped():
+			p.state = Stopped
+			p.status = statusSignalOffset + int(ws.StopSignal())
+		case ws.Continued():
+			p.state = Running
+		}
+
+		done := p.state == Done
+		t.cond.Broadcast()
+		t.mu.Unlock()
+
+		if done {
+			return
+		}
+	}
+}
+
+// signal sends a signal to all the processes of a job
+// Jobs with their own process group are signaled as a group, the others process by process
+// since they share the process group of the shell
+func (t *Table) signal(job *Job, sig syscall.Signal) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if job.ownGroup && job.Pgid != 0 {
+		return syscall.Kill(-job.Pgid, sig)
+	}
+
+	for _, p := range job.processes {
+		if p.Pid != 0 && p.state != Done {
+			if err := syscall.Kill(p.Pid, sig); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resume sends SIGCONT to a job and marks its stopped processes as running right away,
+// without waiting for the reaper to notice they were continued
+func (t *Table) resume(job *Job) error {
+	if err := t.signal(job, syscall.SIGCONT); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range job.processes {
+		if p.state == Stopped {
+			p.state = Running
+		}
+	}
+	return nil
+}
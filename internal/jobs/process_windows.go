@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// shellProcessGroup returns 0, Windows has no process groups
+func shellProcessGroup() int {
+	return 0
+}
+
+// catchStopSignal does nothing, Windows has no Ctrl+Z to suspend a process
+func catchStopSignal() {}
+
+// setProcessGroup leaves the process alone, Windows has no process groups, so the processes of a job are signaled one by one
+func (t *Table) setProcessGroup(*Job, *exec.Cmd) bool {
+	return false
+}
+
+// reap waits for a process to exit, which is the only change of state of a process on Windows
+func (t *Table) reap(p *Process, cmd *exec.Cmd) {
+	cmd.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p.state = Done
+	p.status = 1
+	if cmd.ProcessState != nil {
+		p.status = cmd.ProcessState.ExitCode()
+	}
+
+	t.cond.Broadcast()
+}
+
+// signal ends all the processes of a job, killing them being the only signal Windows can send them
+func (t *Table) signal(job *Job, _ syscall.Signal) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range job.processes {
+		if p.Pid == 0 || p.state == Done {
+			continue
+		}
+
+		process, err := os.FindProcess(p.Pid)
+		if err != nil {
+			return err
+		}
+
+		if err := process.Kill(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resume does nothing, the processes of a job are never stopped on Windows
+func (t *Table) resume(*Job) error {
+	return nil
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// giveTerminal prepares the terminal for a job about to own it, saving the modes of the shell (the ones of its prompt)
+// and setting the ones of the job: the modes it had when it was stopped, or else the ones of the terminal when the shell started
+// The caller makes the process group of the job the foreground process group of the terminal
+func (t *Table) giveTerminal(job *Job) {
+	if t.ttyFd < 0 || job.hasTerminal {
+		return
+	}
+
+	t.shellModes = t.getModes()
+
+	modes := job.modes
+	if modes == nil {
+		modes = t.jobModes
+	}
+	t.setModes(modes)
+
+	job.hasTerminal = true
+}
+
+// takeTerminal makes the shell the foreground process group of the terminal again, once its job is done or stopped,
+// and restores the modes of the shell, whatever state the job left the terminal in (e.g. a killed full screen editor)
+// The modes of a stopped job are kept, to be set again when it is resumed
+func (t *Table) takeTerminal(job *Job) {
+	if !job.hasTerminal {
+		return
+	}
+
+	if job.state() == Stopped {
+		job.modes = t.getModes()
+	}
+
+	t.setTerminalOwner(t.shellPgid)
+	t.setModes(t.shellModes)
+
+	job.hasTerminal = false
+}
+
+// reportSignal reports a foreground job killed by a signal, which its exit status alone does not tell apart from an exit code
+// Ctrl+C only ends the line, which the terminal echoed it on, and a broken pipe is the usual end of a pipeline, so they are not reported
+func reportSignal(job *Job) {
+	if job.final == nil || job.final.signal == 0 || job.final.signal == syscall.SIGPIPE {
+		return
+	}
+
+	if job.final.signal == syscall.SIGINT {
+		fmt.Fprintln(os.Stderr)
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, describeSignal(job.final))
+}
+
+// describeSignal returns the description of the signal which killed a process, e.g. `Segmentation fault (core dumped)`
+func describeSignal(p *Process) string {
+	description := p.signal.String()
+	description = strings.ToUpper(description[:1]) + description[1:]
+
+	if p.coreDumped {
+		description += " (core dumped)"
+	}
+	return description
+}
@@ -0,0 +1,58 @@
+//go:build unix
+
+package jobs
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalModes are the modes of the terminal, saved and restored around the jobs owning it
+type terminalModes = unix.Termios
+
+// terminalFd returns the file descriptor of the controlling terminal of the shell, or -1 if stdin is not a terminal
+func terminalFd() int {
+	fd := int(os.Stdin.Fd())
+	if _, err := unix.IoctlGetTermios(fd, ioctlGetTermios); err != nil {
+		return -1
+	}
+	return fd
+}
+
+// setTerminalOwner makes the given process group the foreground process group of the terminal
+func (t *Table) setTerminalOwner(pgid int) error {
+	if t.ttyFd < 0 {
+		return nil
+	}
+
+	// Taking the terminal back from a background process group raises SIGTTOU unless it is ignored
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+
+	return unix.IoctlSetPointerInt(t.ttyFd, unix.TIOCSPGRP, pgid)
+}
+
+// getModes returns the current modes of the terminal, nil if there is no terminal
+func (t *Table) getModes() *terminalModes {
+	if t.ttyFd < 0 {
+		return nil
+	}
+
+	modes, err := unix.IoctlGetTermios(t.ttyFd, ioctlGetTermios)
+	if err != nil {
+		return nil
+	}
+	return modes
+}
+
+// setModes changes the modes of the terminal, once the output written so far is sent
+func (t *Table) setModes(modes *terminalModes) {
+	if t.ttyFd < 0 || modes == nil {
+		return
+	}
+
+	unix.IoctlSetTermios(t.ttyFd, ioctlSetTermiosDrain, modes)
+}
@@ -0,0 +1,22 @@
+package jobs
+
+// terminalModes are the modes of the terminal, which Windows does not hand over to the jobs
+type terminalModes struct{}
+
+// terminalFd returns -1, the console of Windows is never handed to a process group, so there is no job control
+func terminalFd() int {
+	return -1
+}
+
+// setTerminalOwner does nothing, without a terminal
+func (t *Table) setTerminalOwner(int) error {
+	return nil
+}
+
+// getModes returns nil, without a terminal
+func (t *Table) getModes() *terminalModes {
+	return nil
+}
+
+// setModes does nothing, without a terminal
+func (t *Table) setModes(*terminalModes) {}
@@ -0,0 +1,11 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package jobs
+
+import "golang.org/x/sys/unix"
+
+// The requests getting and setting the modes of the terminal, the latter once the output written so far is sent
+const (
+	ioctlGetTermios      = unix.TIOCGETA
+	ioctlSetTermiosDrain = unix.TIOCSETAW
+)
@@ -0,0 +1,9 @@
+package jobs
+
+import "golang.org/x/sys/unix"
+
+// The requests getting and setting the modes of the terminal, the latter once the output written so far is sent
+const (
+	ioctlGetTermios      = unix.TCGETS
+	ioctlSetTermiosDrain = unix.TCSETSW
+)
@@ -0,0 +1,8 @@
+package jobs
+
+import "syscall"
+
+// waitOptions make the reaper notice the processes stopped by a signal, along with the ones which exit
+// NetBSD reports the continued processes with a status that syscall.WaitStatus does not decode, so they are left out,
+// resume marks the processes it continues as running itself
+const waitOptions = syscall.WUNTRACED
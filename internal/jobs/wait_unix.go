@@ -0,0 +1,8 @@
+//go:build unix && !netbsd
+
+package jobs
+
+import "syscall"
+
+// waitOptions make the reaper notice the processes stopped or continued by a signal, along with the ones which exit
+const waitOptions = syscall.WUNTRACED | syscall.WCONTINUED
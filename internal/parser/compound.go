@@ -0,0 +1,325 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// compoundCommandWords are the reserved words starting a compound command
+var compoundCommandWords = []string{"if", "while", "until", "for", "case", "{"}
+
+// reservedWords are the other reserved words, which cannot start a command
+var reservedWords = []string{"then", "elif", "else", "fi", "do", "done", "in", "esac", "}"}
+
+// commandPrefixWords are the reserved words followed by a command, or by the words of a for or case, on the same line or the next one
+var commandPrefixWords = []string{"if", "then", "elif", "else", "while", "until", "do", "in", "{"}
+
+// caseTerminators are the operators ending the items of a case command
+var caseTerminators = []string{types.CaseBreak, types.CaseFallthrough, types.CaseContinue}
+
+// caseBodyTerminators end the body of an item of a case command, the last item being allowed to end right before esac
+var caseBodyTerminators = []string{types.CaseBreak, types.CaseFallthrough, types.CaseContinue, "esac"}
+
+// parseCompoundCommand parses a compound command, starting with its reserved word, followed by its redirections
+func (cp *commandParser) parseCompoundCommand() (types.PipelineStage, error) {
+	var compound types.CompoundCommand
+	var err error
+
+	switch cp.next().text {
+	case "if":
+		compound.If, err = cp.parseIf()
+	case "while":
+		compound.While, err = cp.parseWhile(false)
+	case "until":
+		compound.While, err = cp.parseWhile(true)
+	case "for":
+		compound.For, compound.ArithmeticFor, err = cp.parseFor()
+	case "case":
+		compound.Case, err = cp.parseCase()
+	case "{":
+		compound.Group, err = cp.parseGroup()
+	}
+
+	if err != nil {
+		return types.PipelineStage{}, err
+	}
+
+	return cp.parseCompoundRedirections(&compound)
+}
+
+// parseIf parses the rest of an if command: its conditions and bodies, up to fi
+func (cp *commandParser) parseIf() (*types.IfClause, error) {
+	var clause types.IfClause
+
+	for {
+		condition, err := cp.parseBody("then")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := cp.expect("then"); err != nil {
+			return nil, err
+		}
+
+		body, err := cp.parseBody("elif", "else", "fi")
+		if err != nil {
+			return nil, err
+		}
+
+		clause.Conditions = append(clause.Conditions, condition)
+		clause.Bodies = append(clause.Bodies, body)
+
+		if !cp.isReservedWord("elif") {
+			break
+		}
+		cp.next()
+	}
+
+	if cp.isReservedWord("else") {
+		cp.next()
+
+		body, err := cp.parseBody("fi")
+		if err != nil {
+			return nil, err
+		}
+		clause.Else = &body
+	}
+
+	return &clause, cp.expect("fi")
+}
+
+// parseGroup parses the rest of a group of commands: its list of commands, up to }
+func (cp *commandParser) parseGroup() (*types.ParsedPrompt, error) {
+	body, err := cp.parseBody("}")
+	if err != nil {
+		return nil, err
+	}
+
+	return &body, cp.expect("}")
+}
+
+// parseWhile parses the rest of a while or until loop: its condition and its body, up to done
+func (cp *commandParser) parseWhile(until bool) (*types.WhileClause, error) {
+	condition, err := cp.parseBody("do")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := cp.parseLoopBody()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.WhileClause{Until: until, Condition: condition, Body: body}, nil
+}
+
+// parseFor parses the rest of a for loop, either over words, for NAME [in WORDS], or C-style, for ((init; condition; update))
+func (cp *commandParser) parseFor() (*types.ForClause, *types.ArithmeticForClause, error) {
+	tok := cp.next()
+	if tok.kind != tokenWord {
+		return nil, nil, unexpectedToken(tok)
+	}
+
+	if length, isArithmetic := ArithmeticLength(tok.text); isArithmetic && length == len(tok.text) {
+		clause, err := cp.parseArithmeticFor(tok.text[2 : length-2])
+		return nil, clause, err
+	}
+
+	if !IsValidName(tok.text) {
+		return nil, nil, fmt.Errorf("`%s': not a valid identifier", tok.text)
+	}
+
+	clause := types.ForClause{Name: tok.text, Words: []string{`"$@"`}}
+
+	cp.skipNewlines()
+	if cp.isReservedWord("in") {
+		cp.next()
+
+		clause.Words = []string{}
+		for cp.peek().kind == tokenWord {
+			clause.Words = append(clause.Words, cp.next().text)
+		}
+
+		if err := cp.expectSeparator(); err != nil {
+			return nil, nil, err
+		}
+	} else if cp.isOperator(";") {
+		cp.next()
+	}
+
+	body, err := cp.parseLoopBody()
+	if err != nil {
+		return nil, nil, err
+	}
+	clause.Body = body
+
+	return &clause, nil, nil
+}
+
+// parseArithmeticFor parses the rest of a C-style for loop, given the expressions between its double parentheses
+func (cp *commandParser) parseArithmeticFor(expressions string) (*types.ArithmeticForClause, error) {
+	parts := strings.Split(expressions, ";")
+	if len(parts) != 3 {
+		return nil, syntaxError("((" + expressions + "))")
+	}
+
+	clause := types.ArithmeticForClause{
+		Init:      strings.TrimSpace(parts[0]),
+		Condition: strings.TrimSpace(parts[1]),
+		Update:    strings.TrimSpace(parts[2]),
+	}
+
+	if cp.isOperator(";") {
+		cp.next()
+	}
+
+	body, err := cp.parseLoopBody()
+	if err != nil {
+		return nil, err
+	}
+	clause.Body = body
+
+	return &clause, nil
+}
+
+// parseLoopBody parses the body of a loop, do ... done
+func (cp *commandParser) parseLoopBody() (types.ParsedPrompt, error) {
+	cp.skipNewlines()
+	if err := cp.expect("do"); err != nil {
+		return types.ParsedPrompt{}, err
+	}
+
+	body, err := cp.parseBody("done")
+	if err != nil {
+		return body, err
+	}
+
+	return body, cp.expect("done")
+}
+
+// parseCase parses the rest of a case command: its word and its items, up to esac
+// Each item is a list of patterns separated by |, optionally starting with (, ending with ) and followed by its body
+func (cp *commandParser) parseCase() (*types.CaseClause, error) {
+	tok := cp.next()
+	if tok.kind != tokenWord {
+		return nil, unexpectedToken(tok)
+	}
+
+	clause := types.CaseClause{Word: tok.text}
+
+	cp.skipNewlines()
+	if err := cp.expect("in"); err != nil {
+		return nil, err
+	}
+
+	for {
+		cp.skipNewlines()
+		if cp.isReservedWord("esac") {
+			cp.next()
+			break
+		}
+
+		if cp.isOperator("(") {
+			cp.next()
+		}
+
+		var item types.CaseItem
+		for {
+			tok := cp.next()
+			if tok.kind != tokenWord {
+				return nil, unexpectedToken(tok)
+			}
+			item.Patterns = append(item.Patterns, tok.text)
+
+			if !cp.isOperator("|") {
+				break
+			}
+			cp.next()
+		}
+
+		if tok := cp.next(); tok.kind != tokenOperator || tok.text != ")" {
+			return nil, unexpectedToken(tok)
+		}
+
+		body, err := cp.parseList(caseBodyTerminators...)
+		if err != nil {
+			return nil, err
+		}
+		item.Body = body
+
+		// The last item may end without a terminator, right before esac
+		item.Terminator = types.CaseBreak
+		if cp.isOperator(caseTerminators...) {
+			item.Terminator = cp.next().text
+		} else if !cp.isReservedWord("esac") {
+			return nil, unexpectedToken(cp.peek())
+		}
+
+		clause.Items = append(clause.Items, item)
+	}
+
+	return &clause, nil
+}
+
+// parseCompoundRedirections parses the redirections following a compound command, which apply to the whole command
+func (cp *commandParser) parseCompoundRedirections(compound *types.CompoundCommand) (types.PipelineStage, error) {
+	if cp.peek().kind != tokenWord {
+		return types.PipelineStage{Compound: compound}, nil
+	}
+
+	tok := cp.peek()
+
+	stage, err := cp.parseSimpleCommand()
+	if err != nil {
+		return stage, err
+	}
+
+	if len(stage.Assignments) > 0 || len(stage.Tokens) > 0 || stage.Arithmetic != "" || stage.Conditional != nil {
+		return stage, syntaxError(tok.text)
+	}
+
+	stage.Compound = compound
+
+	return stage, nil
+}
+
+// parseBody parses the list of commands of a compound command, up to one of the reserved words ending it
+// Unlike the items of a case command, such a list cannot be empty
+func (cp *commandParser) parseBody(terminators ...string) (types.ParsedPrompt, error) {
+	body, err := cp.parseList(terminators...)
+	if err != nil {
+		return body, err
+	}
+
+	if len(body.Chains) == 0 {
+		return body, unexpectedToken(cp.peek())
+	}
+
+	return body, nil
+}
+
+// expect consumes the given reserved word, which must be the current token
+func (cp *commandParser) expect(word string) error {
+	if !cp.isReservedWord(word) {
+		return unexpectedToken(cp.peek())
+	}
+
+	cp.next()
+	return nil
+}
+
+// expectSeparator consumes the ; or the newlines ending the words of a for loop
+func (cp *commandParser) expectSeparator() error {
+	switch tok := cp.peek(); {
+	case tok.kind == tokenOperator && tok.text == ";":
+		cp.next()
+	case tok.kind == tokenNewline:
+	default:
+		return unexpectedToken(tok)
+	}
+
+	cp.skipNewlines()
+	return nil
+}
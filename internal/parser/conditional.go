@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// conditionalOperators are the operators of a conditional command [[ ... ]] which need no blanks around them
+var conditionalOperators = []string{types.OperatorAnd, types.OperatorOr, "(", ")", "<", ">"}
+
+// isConditionalStart checks whether the input starts with [[ followed by a blank, opening a conditional command
+func isConditionalStart(input string) bool {
+	return len(input) > 2 && strings.HasPrefix(input, "[[") && strings.ContainsRune(" \t\n", rune(input[2]))
+}
+
+// ConditionalLength returns the length of the conditional command at the beginning of the input, [[ ... ]],
+// and whether it ends in the input. The closing ]] is a word of its own, outside of quotes
+func ConditionalLength(input string) (int, bool) {
+	if !isConditionalStart(input) {
+		return 0, false
+	}
+
+	inSingleQuote := false
+	inDoubleQuote := false
+
+	for i := 2; i < len(input); i++ {
+		char := input[i]
+
+		if !inSingleQuote && (char == '`' || strings.HasPrefix(input[i:], "$(")) {
+			length, err := SubstitutionLength(input[i:])
+			if err != nil {
+				return 0, false
+			}
+
+			i += length - 1
+			continue
+		}
+
+		switch {
+		case char == '\\' && !inSingleQuote:
+			i++
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		case inSingleQuote || inDoubleQuote:
+			continue
+		case strings.HasPrefix(input[i:], "]]") && strings.ContainsRune(" \t\n", rune(input[i-1])):
+			if end := i + 2; end == len(input) || strings.ContainsRune(" \t\n;&|)", rune(input[end])) {
+				return end, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// splitConditional splits the expression of a conditional command into its raw words and operators,
+// the words keeping their quotes so that a quoted operator is a plain string
+// The word following =~ is a regular expression, whose parentheses and | do not end it
+func splitConditional(expr string) []string {
+	var words []string
+
+	for i := 0; i < len(expr); {
+		if strings.ContainsRune(" \t\n", rune(expr[i])) {
+			i++
+			continue
+		}
+
+		isRegex := len(words) > 0 && words[len(words)-1] == "=~"
+		if operator := matchOperator(expr[i:], conditionalOperators); operator != "" && !isRegex {
+			words = append(words, operator)
+			i += len(operator)
+			continue
+		}
+
+		length := conditionalWordLength(expr[i:], isRegex)
+		words = append(words, expr[i:i+length])
+		i += length
+	}
+
+	return words
+}
+
+// conditionalWordLength returns the length of the word at the beginning of the expression of a conditional command,
+// up to an unquoted blank or operator. In a regular expression, only a closing parenthesis without its opening one ends the word
+func conditionalWordLength(expr string, isRegex bool) int {
+	inSingleQuote := false
+	inDoubleQuote := false
+	depth := 0
+
+	for i := 0; i < len(expr); i++ {
+		char := expr[i]
+
+		if !inSingleQuote && (char == '`' || strings.HasPrefix(expr[i:], "$(")) {
+			if length, err := SubstitutionLength(expr[i:]); err == nil {
+				i += length - 1
+				continue
+			}
+		}
+
+		if !inSingleQuote && strings.HasPrefix(expr[i:], "${") {
+			if length, err := ParameterLength(expr[i:], inDoubleQuote); err == nil {
+				i += length - 1
+				continue
+			}
+		}
+
+		switch {
+		case char == '\\' && !inSingleQuote:
+			i++
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		case inSingleQuote || inDoubleQuote:
+		case strings.ContainsRune(" \t\n", rune(char)):
+			return i
+		case isRegex && char == '(':
+			depth++
+		case isRegex && char == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case !isRegex && matchOperator(expr[i:], conditionalOperators) != "":
+			return i
+		}
+	}
+
+	return len(expr)
+}
+
+// atCommandStart checks whether the next word of the lexer starts a command, following an operator,
+// a newline or a reserved word followed by a command, which is where [[ opens a conditional command
+func (l *lexer) atCommandStart() bool {
+	if len(l.tokens) == 0 {
+		return true
+	}
+
+	last := l.tokens[len(l.tokens)-1]
+	return last.kind != tokenWord || last.text == "!" || slices.Contains(commandPrefixWords, last.text)
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// functionWord is the reserved word starting a function definition, which may then omit the parentheses
+const functionWord = "function"
+
+// isFunctionDefinition checks whether the current word starts a function definition of the form name()
+func (cp *commandParser) isFunctionDefinition() bool {
+	if cp.pos+2 >= len(cp.tokens) {
+		return false
+	}
+
+	open, closing := cp.tokens[cp.pos+1], cp.tokens[cp.pos+2]
+	return open.kind == tokenOperator && open.text == "(" && closing.kind == tokenOperator && closing.text == ")"
+}
+
+// parseFunctionDefinition parses a function definition, name() or function name [()], followed by its body
+// The body is a compound command, usually a group { ...; }, which may start on the next line
+func (cp *commandParser) parseFunctionDefinition() (types.PipelineStage, error) {
+	start := cp.pos
+
+	if cp.isReservedWord(functionWord) {
+		cp.next()
+	}
+
+	name := cp.next()
+	if name.kind != tokenWord {
+		return types.PipelineStage{}, unexpectedToken(name)
+	}
+
+	if !isValidFunctionName(name.text) {
+		return types.PipelineStage{}, fmt.Errorf("`%s': not a valid identifier", name.text)
+	}
+
+	if cp.isOperator("(") {
+		cp.next()
+		if tok := cp.next(); tok.kind != tokenOperator || tok.text != ")" {
+			return types.PipelineStage{}, unexpectedToken(tok)
+		}
+	}
+
+	cp.skipNewlines()
+	if !cp.isReservedWord(compoundCommandWords...) {
+		return types.PipelineStage{}, unexpectedToken(cp.peek())
+	}
+
+	body, err := cp.parseCompoundCommand()
+	if err != nil {
+		return types.PipelineStage{}, err
+	}
+
+	return types.PipelineStage{
+		Function: &types.FunctionDefinition{
+			Name: name.text,
+			Body: body,
+			Text: joinTokens(cp.tokens[start:cp.pos]),
+		},
+	}, nil
+}
+
+// isValidFunctionName checks that a word can name a function, which unlike a variable may contain characters such as - or .
+// It cannot be quoted nor contain expansions or assignments
+func isValidFunctionName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "'\"\\$`=") && !isDigit(name[0])
+}
@@ -0,0 +1,300 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// tokenKind tells what a token of the input is
+type tokenKind int
+
+const (
+	tokenWord     tokenKind = iota // a raw word, keeping its quotes, substitutions and redirection operators
+	tokenOperator                  // a control operator, such as ;, &&, | or ;;
+	tokenNewline
+	tokenEOF
+)
+
+// controlOperators are the operators separating commands, the longer ones coming first
+var controlOperators = []string{
+	types.CaseContinue, types.CaseBreak, types.CaseFallthrough,
+	types.OperatorAnd, types.OperatorOr, ";", "&", "|", "(", ")",
+}
+
+// token is a word or an operator of the input
+type token struct {
+	kind tokenKind
+	text string
+
+	hereDocs  []string // the bodies of the here-documents started by the word, read from the lines following it
+	fromAlias bool     // the word comes from an expanded alias, so it is not looked up as an alias again
+}
+
+// pendingHereDoc is a here-document whose body starts on the line following its command
+type pendingHereDoc struct {
+	token     int // the index of the token the body is attached to
+	delimiter string
+	stripTabs bool
+}
+
+// lexer splits the input into tokens, reading the bodies of the here-documents as it goes past the end of their line
+type lexer struct {
+	input  string
+	pos    int
+	tokens []token
+
+	pending           []pendingHereDoc
+	awaitingDelimiter *pendingHereDoc // a here-document operator ended its word, its delimiter is the next word
+}
+
+// tokenize splits the input into words and operators, ending with an EOF token
+// Blanks separate the words and are dropped, as are comments and escaped newlines
+// It returns ErrIncompleteInput when the input ends inside quotes, with an escaped newline or before the body of a here-document is complete
+func tokenize(input string) ([]token, error) {
+	l := lexer{input: input}
+
+	for {
+		for l.pos < len(l.input) && (l.input[l.pos] == ' ' || l.input[l.pos] == '\t') {
+			l.pos++
+		}
+
+		if l.pos >= len(l.input) {
+			break
+		}
+
+		switch char := l.input[l.pos]; {
+		case char == '\n':
+			l.pos++
+			l.awaitingDelimiter = nil
+			l.tokens = append(l.tokens, token{kind: tokenNewline, text: "\n"})
+
+			if err := l.readHereDocs(); err != nil {
+				return nil, err
+			}
+		case char == '#':
+			// A comment runs up to the end of the line, the newline itself still separating commands
+			if end := strings.IndexByte(l.input[l.pos:], '\n'); end != -1 {
+				l.pos += end
+			} else {
+				l.pos = len(l.input)
+			}
+		case strings.HasPrefix(l.input[l.pos:], "\\\n"):
+			if l.pos+2 == len(l.input) {
+				return nil, ErrIncompleteInput // The escaped newline continues the input on the next line
+			}
+			l.pos += 2
+		default:
+			if operator := l.operator(); operator != "" {
+				l.pos += len(operator)
+				l.awaitingDelimiter = nil
+				l.tokens = append(l.tokens, token{kind: tokenOperator, text: operator})
+				continue
+			}
+
+			if err := l.word(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(l.pending) > 0 {
+		return nil, ErrIncompleteInput
+	}
+
+	return append(l.tokens, token{kind: tokenEOF}), nil
+}
+
+// operator returns the control operator at the current position, or an empty string if a word starts there
+// Redirection operators starting with & (e.g. &>) and arithmetic commands ((...)) are words
+func (l *lexer) operator() string {
+	rest := l.input[l.pos:]
+
+	if matchOperator(rest, redirectionOperators) != "" {
+		return ""
+	}
+
+	if _, isArithmetic := ArithmeticLength(rest); isArithmetic {
+		return ""
+	}
+
+	return matchOperator(rest, controlOperators)
+}
+
+// word reads the word at the current position, up to an unquoted blank, newline or control operator
+// Quoted text, command substitutions, parameter expansions and redirection operators are part of the word
+func (l *lexer) word() error {
+	var sb strings.Builder
+
+	inSingleQuote := false
+	inDoubleQuote := false
+
+	// hereDocStart is the position in the word where the delimiter of a here-document starts, -1 if there is none
+	hereDocStart, stripTabs := -1, false
+	endHereDoc := func() {
+		if hereDocStart == -1 {
+			return
+		}
+
+		if delimiter := sb.String()[hereDocStart:]; delimiter != "" {
+			l.addHereDoc(delimiter, stripTabs)
+		} else {
+			l.awaitingDelimiter = &pendingHereDoc{token: len(l.tokens), stripTabs: stripTabs}
+		}
+		hereDocStart = -1
+	}
+
+	start := l.pos
+
+loop:
+	for l.pos < len(l.input) {
+		char := l.input[l.pos]
+		rest := l.input[l.pos:]
+
+		if inSingleQuote {
+			inSingleQuote = char != '\''
+			sb.WriteByte(char)
+			l.pos++
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(rest, "\\\n"):
+			if len(rest) == 2 {
+				return ErrIncompleteInput // The line following the escaped newline is still to be read
+			}
+
+			l.pos += 2 // An escaped newline joins the lines
+			continue
+		case char == '\\':
+			if l.pos+1 == len(l.input) {
+				return ErrIncompleteInput // A trailing backslash continues the input on the next line
+			}
+
+			sb.WriteString(rest[:2])
+			l.pos += 2
+			continue
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = true
+		case char == '"':
+			inDoubleQuote = !inDoubleQuote
+		case char == '`' || strings.HasPrefix(rest, "$("):
+			length, err := SubstitutionLength(rest)
+			if err != nil {
+				return ErrIncompleteInput
+			}
+
+			sb.WriteString(rest[:length])
+			l.pos += length
+			continue
+		case strings.HasPrefix(rest, "${"):
+			if length, err := ParameterLength(rest, inDoubleQuote); err == nil {
+				sb.WriteString(rest[:length])
+				l.pos += length
+				continue
+			}
+		case inDoubleQuote:
+		case char == ' ' || char == '\t' || char == '\n':
+			break loop
+		case l.pos == start && isConditionalStart(rest) && l.atCommandStart():
+			// A conditional command [[ ... ]] is a single word, its operators such as && or < not separating commands
+			length, isComplete := ConditionalLength(rest)
+			if !isComplete {
+				return ErrIncompleteInput
+			}
+
+			sb.WriteString(rest[:length])
+			l.pos += length
+			continue
+		case l.pos == start && strings.HasPrefix(rest, "(("):
+			if length, isArithmetic := ArithmeticLength(rest); isArithmetic {
+				sb.WriteString(rest[:length])
+				l.pos += length
+				continue
+			}
+			break loop
+		default:
+			if redirection := matchOperator(rest, redirectionOperators); redirection != "" {
+				endHereDoc()
+
+				sb.WriteString(redirection)
+				l.pos += len(redirection)
+
+				if redirection == types.RedirectHereDoc || redirection == types.RedirectHereDocTab {
+					hereDocStart, stripTabs = sb.Len(), redirection == types.RedirectHereDocTab
+				}
+				continue
+			}
+
+			if matchOperator(rest, controlOperators) != "" {
+				break loop
+			}
+		}
+
+		sb.WriteByte(char)
+		l.pos++
+	}
+
+	if inSingleQuote || inDoubleQuote {
+		return ErrIncompleteInput
+	}
+
+	// A word following a here-document operator on its own (e.g. << EOF) is its delimiter
+	if l.awaitingDelimiter != nil {
+		l.awaitingDelimiter.delimiter = removeQuotes(sb.String())
+		l.pending = append(l.pending, *l.awaitingDelimiter)
+		l.awaitingDelimiter = nil
+	}
+
+	endHereDoc()
+
+	l.tokens = append(l.tokens, token{kind: tokenWord, text: sb.String()})
+	return nil
+}
+
+// addHereDoc records a here-document of the word being read, whose body follows the end of the line
+func (l *lexer) addHereDoc(delimiter string, stripTabs bool) {
+	l.pending = append(l.pending, pendingHereDoc{
+		token:     len(l.tokens),
+		delimiter: removeQuotes(delimiter),
+		stripTabs: stripTabs,
+	})
+}
+
+// readHereDocs reads the bodies of the pending here-documents, in order, from the lines at the current position
+// Each body goes up to the line holding only its delimiter, with <<- the leading tabs of the lines being removed
+func (l *lexer) readHereDocs() error {
+	for len(l.pending) > 0 {
+		hereDoc := l.pending[0]
+
+		var body strings.Builder
+		for {
+			if l.pos >= len(l.input) {
+				return ErrIncompleteInput
+			}
+
+			line := l.input[l.pos:]
+			if end := strings.IndexByte(line, '\n'); end != -1 {
+				line = line[:end]
+				l.pos += end + 1
+			} else {
+				l.pos = len(l.input)
+			}
+
+			if hereDoc.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+
+			if line == hereDoc.delimiter {
+				break
+			}
+
+			body.WriteString(line + "\n")
+		}
+
+		l.tokens[hereDoc.token].hereDocs = append(l.tokens[hereDoc.token].hereDocs, body.String())
+		l.pending = l.pending[1:]
+	}
+
+	return nil
+}
@@ -0,0 +1,851 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+var (
+	errUnterminatedSingleQuote  = errors.New("unterminated single quotes")
+	errUnterminatedDoubleQuote  = errors.New("unterminated double quotes")
+	errUnterminatedSubstitution = errors.New("unterminated command substitution")
+	errUnterminatedBackquote    = errors.New("unterminated backquotes")
+	errUnterminatedParameter    = errors.New("unterminated parameter expansion")
+	errMissingRedirectTarget    = errors.New("syntax error: missing redirection target")
+
+	// ErrIncompleteInput is returned while more lines are needed to complete the input, such as the body of a here-document
+	ErrIncompleteInput = errors.New("unexpected end of input")
+)
+
+// commandSeparators are the operators that end a command, the longer ones coming first
+var commandSeparators = []string{types.OperatorAnd, types.OperatorOr, ";", "&", "|"}
+
+// redirectionOperators are the operators of the redirections, the longer ones coming first
+// Some of them contain & or | (e.g. 2>&1), they never separate commands
+var redirectionOperators = []string{
+	types.RedirectHereString, types.RedirectHereDocTab, types.RedirectAppendAll,
+	types.RedirectHereDoc, types.RedirectReadWrite, types.RedirectDupInput,
+	types.RedirectAppend, types.RedirectClobber, types.RedirectDupOutput, types.RedirectOutputAll,
+	types.RedirectInput, types.RedirectOutput,
+}
+
+// Parser turns input lines into the chains, pipelines and commands to execute
+type Parser struct {
+	aliases *types.Aliases
+}
+
+func NewParser(aliases *types.Aliases) *Parser {
+	return &Parser{
+		aliases: aliases,
+	}
+}
+
+// Parse parses the input, which spans several lines when it holds compound commands or here-documents
+// The lines following a command line are the bodies of its here-documents, if it has any
+// If the input ends in the middle of a command, such as before the fi of an if or the delimiter of a here-document,
+// it returns ErrIncompleteInput, so that more lines can be read
+func (p *Parser) Parse(input string) (types.ParsedPrompt, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return types.ParsedPrompt{}, err
+	}
+
+	cp := commandParser{parser: p, tokens: tokens}
+
+	parsedPrompt, err := cp.parseList()
+	if err != nil {
+		return types.ParsedPrompt{}, err
+	}
+
+	if tok := cp.peek(); tok.kind != tokenEOF {
+		return types.ParsedPrompt{}, unexpectedToken(tok)
+	}
+
+	return parsedPrompt, nil
+}
+
+// commandParser builds the commands of the input from its tokens, following the grammar of the shell
+type commandParser struct {
+	parser *Parser
+	tokens []token
+	pos    int
+}
+
+// peek returns the current token without consuming it
+func (cp *commandParser) peek() token {
+	return cp.tokens[cp.pos]
+}
+
+// next consumes the current token and returns it, the EOF token being never consumed
+func (cp *commandParser) next() token {
+	tok := cp.tokens[cp.pos]
+	if tok.kind != tokenEOF {
+		cp.pos++
+	}
+	return tok
+}
+
+// isOperator checks whether the current token is one of the given control operators
+func (cp *commandParser) isOperator(operators ...string) bool {
+	tok := cp.peek()
+	return tok.kind == tokenOperator && slices.Contains(operators, tok.text)
+}
+
+// isReservedWord checks whether the current token is one of the given reserved words
+// Reserved words are only recognized where a command starts, they are plain arguments anywhere else
+func (cp *commandParser) isReservedWord(words ...string) bool {
+	tok := cp.peek()
+	return tok.kind == tokenWord && slices.Contains(words, tok.text)
+}
+
+// skipNewlines consumes the newlines at the current position, which may separate the parts of a command
+func (cp *commandParser) skipNewlines() {
+	for cp.peek().kind == tokenNewline {
+		cp.next()
+	}
+}
+
+// parseList parses the chains separated by ;, & or newlines, up to the end of the input or to one of the terminators,
+// reserved words or operators ending the list in the command it belongs to (e.g. then, fi or ;;)
+func (cp *commandParser) parseList(terminators ...string) (types.ParsedPrompt, error) {
+	var list types.ParsedPrompt
+
+	for {
+		cp.skipNewlines()
+
+		tok := cp.peek()
+		if tok.kind == tokenEOF || (tok.kind != tokenNewline && slices.Contains(terminators, tok.text)) {
+			return list, nil
+		}
+
+		chain, err := cp.parseChain()
+		if err != nil {
+			return list, err
+		}
+
+		switch tok := cp.peek(); {
+		case tok.kind == tokenOperator && (tok.text == ";" || tok.text == "&"):
+			chain.Background = tok.text == "&"
+			cp.next()
+		case tok.kind == tokenNewline || tok.kind == tokenEOF || slices.Contains(terminators, tok.text):
+		default:
+			return list, unexpectedToken(tok)
+		}
+
+		list.Chains = append(list.Chains, chain)
+	}
+}
+
+// parseChain parses the pipelines joined by && or || operators, which may be followed by newlines
+func (cp *commandParser) parseChain() (types.Chain, error) {
+	var chain types.Chain
+	start := cp.pos
+
+	for {
+		pipeline, err := cp.parsePipeline()
+		if err != nil {
+			return chain, err
+		}
+		chain.Pipelines = append(chain.Pipelines, pipeline)
+
+		if !cp.isOperator(types.OperatorAnd, types.OperatorOr) {
+			break
+		}
+
+		chain.Operators = append(chain.Operators, cp.next().text)
+		cp.skipNewlines()
+	}
+
+	chain.Text = joinTokens(cp.tokens[start:cp.pos])
+
+	return chain, nil
+}
+
+// parsePipeline parses the commands joined by pipes, which may be followed by newlines
+func (cp *commandParser) parsePipeline() (types.Pipeline, error) {
+	var pipeline types.Pipeline
+	start := cp.pos
+
+	for {
+		stage, err := cp.parseCommand()
+		if err != nil {
+			return pipeline, err
+		}
+		pipeline.Stages = append(pipeline.Stages, stage)
+
+		if !cp.isOperator("|") {
+			break
+		}
+
+		cp.next()
+		cp.skipNewlines()
+	}
+
+	pipeline.Text = joinTokens(cp.tokens[start:cp.pos])
+
+	return pipeline, nil
+}
+
+// parseCommand parses a single command, either a compound command, a function definition or a simple command
+// The first word of a simple command is replaced with its alias command, if one exists
+func (cp *commandParser) parseCommand() (types.PipelineStage, error) {
+	tok := cp.peek()
+
+	switch {
+	case tok.kind != tokenWord:
+		return types.PipelineStage{}, unexpectedToken(tok)
+	case cp.isReservedWord(compoundCommandWords...):
+		return cp.parseCompoundCommand()
+	case cp.isReservedWord(functionWord) || cp.isFunctionDefinition():
+		return cp.parseFunctionDefinition()
+	case cp.isReservedWord(reservedWords...):
+		return types.PipelineStage{}, unexpectedToken(tok)
+	}
+
+	if expanded, err := cp.expandAlias(); err != nil {
+		return types.PipelineStage{}, err
+	} else if expanded {
+		return cp.parseCommand()
+	}
+
+	return cp.parseSimpleCommand()
+}
+
+// parseSimpleCommand parses the words of a simple command, up to the next operator
+func (cp *commandParser) parseSimpleCommand() (types.PipelineStage, error) {
+	var words, hereDocs []string
+	for cp.peek().kind == tokenWord {
+		tok := cp.next()
+		words = append(words, tok.text)
+		hereDocs = append(hereDocs, tok.hereDocs...)
+	}
+
+	stage, err := cp.parser.parseStage(strings.Join(words, " "))
+	if err != nil {
+		return stage, err
+	}
+
+	// The bodies of the here-documents were read in the order of their operators
+	for idx, r := range stage.Redirections {
+		if (r.Operator == types.RedirectHereDoc || r.Operator == types.RedirectHereDocTab) && len(hereDocs) > 0 {
+			stage.Redirections[idx].Document = hereDocs[0]
+			hereDocs = hereDocs[1:]
+		}
+	}
+
+	return stage, nil
+}
+
+// expandAlias replaces the current word with the tokens of its alias command, if it is an alias
+// The alias command has its own aliases expanded first, so an alias referring to itself (e.g. ls='ls -la')
+// is not expanded again. It returns whether the word was replaced
+func (cp *commandParser) expandAlias() (bool, error) {
+	tok := cp.peek()
+	if tok.fromAlias || cp.parser.aliases == nil {
+		return false, nil
+	}
+
+	aliasCommand, exists := (*cp.parser.aliases)[tok.text]
+	if !exists {
+		return false, nil
+	}
+
+	tokens, err := tokenize(cp.parser.expandAliases(aliasCommand, map[string]bool{tok.text: true}))
+	if err != nil {
+		return false, err
+	}
+
+	tokens = tokens[:len(tokens)-1] // Without its EOF token
+	for idx := range tokens {
+		tokens[idx].fromAlias = true
+	}
+
+	cp.tokens = slices.Replace(cp.tokens, cp.pos, cp.pos+1, tokens...)
+
+	return true, nil
+}
+
+// joinTokens returns the text of a command made of the given tokens, with its words separated by single spaces
+// Newlines between commands are shown as ;
+func joinTokens(tokens []token) string {
+	var sb strings.Builder
+
+	for idx, tok := range tokens {
+		switch {
+		case tok.kind == tokenNewline:
+			previous := tokens[max(idx-1, 0)]
+			if idx > 0 && previous.kind == tokenWord && !slices.Contains(commandPrefixWords, previous.text) {
+				sb.WriteString(";")
+			}
+		case tok.kind == tokenOperator && (tok.text == ";" || tok.text == "&" || tok.text == ")" || strings.HasPrefix(tok.text, ";;") || tok.text == types.CaseFallthrough):
+			sb.WriteString(tok.text)
+		default:
+			if sb.Len() > 0 {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(tok.text)
+		}
+	}
+
+	return sb.String()
+}
+
+// removeQuotes removes the quotes and escape characters of a raw word, without expanding it
+func removeQuotes(word string) string {
+	var sb strings.Builder
+
+	inSingleQuote := false
+	inDoubleQuote := false
+
+	for i := 0; i < len(word); i++ {
+		char := word[i]
+
+		switch {
+		case char == '\\' && !inSingleQuote && i+1 < len(word):
+			i++
+			sb.WriteByte(word[i])
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		default:
+			sb.WriteByte(char)
+		}
+	}
+
+	return sb.String()
+}
+
+// expandAliases replaces the first word of every command in the input with its alias command, if one exists
+// The expanded aliases are tracked so an alias referring to itself (e.g. ls='ls -la') is not expanded again
+func (p *Parser) expandAliases(input string, expandedAliases map[string]bool) string {
+	if p.aliases == nil || len(*p.aliases) == 0 {
+		return input
+	}
+
+	segments, operators := splitUnquoted(input, commandSeparators...)
+
+	var sb strings.Builder
+	for idx, segment := range segments {
+		sb.WriteString(p.expandAlias(segment, expandedAliases))
+		if idx < len(operators) {
+			sb.WriteString(operators[idx])
+		}
+	}
+
+	return sb.String()
+}
+
+// expandAlias replaces the first word of a single command with its alias command, keeping the rest of the command as is
+func (p *Parser) expandAlias(input string, expandedAliases map[string]bool) string {
+	trimmed := strings.TrimLeft(input, " \t")
+
+	name := trimmed
+	if end := strings.IndexAny(trimmed, " \t"); end != -1 {
+		name = trimmed[:end]
+	}
+
+	if name == "" || expandedAliases[name] {
+		return input
+	}
+
+	aliasCommand, exists := (*p.aliases)[name]
+	if !exists {
+		return input
+	}
+
+	expandedAliases[name] = true
+	defer delete(expandedAliases, name)
+
+	leading := input[:len(input)-len(trimmed)]
+	return leading + p.expandAliases(aliasCommand, expandedAliases) + trimmed[len(name):]
+}
+
+// splitUnquoted splits the input on every operator that is not quoted or escaped, returning the segments
+// and the operators found between them. Operators are matched in the given order, so longer ones must come first
+func splitUnquoted(input string, operators ...string) ([]string, []string) {
+	var segments, found []string
+
+	inSingleQuote := false
+	inDoubleQuote := false
+	escaping := false
+	start := 0
+
+	for i := 0; i < len(input); i++ {
+		char := input[i]
+
+		if escaping {
+			escaping = false
+			continue
+		}
+
+		// Command substitutions are part of the command, even if they contain operators
+		if !inSingleQuote && (char == '`' || strings.HasPrefix(input[i:], "$(")) {
+			if length, err := SubstitutionLength(input[i:]); err == nil {
+				i += length - 1
+				continue
+			}
+		}
+
+		// So are parameter expansions, such as ${VAR:-a;b}
+		if !inSingleQuote && strings.HasPrefix(input[i:], "${") {
+			if length, err := ParameterLength(input[i:], inDoubleQuote); err == nil {
+				i += length - 1
+				continue
+			}
+		}
+
+		// So are arithmetic commands, whose expression may contain operators such as && or <
+		if !inSingleQuote && !inDoubleQuote && strings.HasPrefix(input[i:], "((") {
+			if length, isArithmetic := ArithmeticLength(input[i:]); isArithmetic {
+				i += length - 1
+				continue
+			}
+		}
+
+		switch char {
+		case '\\':
+			escaping = !inSingleQuote
+		case '\'':
+			if !inDoubleQuote {
+				inSingleQuote = !inSingleQuote
+			}
+		case '"':
+			if !inSingleQuote {
+				inDoubleQuote = !inDoubleQuote
+			}
+		default:
+			if inSingleQuote || inDoubleQuote {
+				continue
+			}
+
+			// Redirection operators such as >& or >| are part of the command, even if they contain an operator
+			if redirection := matchOperator(input[i:], redirectionOperators); redirection != "" {
+				i += len(redirection) - 1
+				continue
+			}
+
+			if operator := matchOperator(input[i:], operators); operator != "" {
+				segments = append(segments, input[start:i])
+				found = append(found, operator)
+				i += len(operator) - 1
+				start = i + 1
+			}
+		}
+	}
+
+	segments = append(segments, input[start:])
+
+	return segments, found
+}
+
+// SubstitutionLength returns the length of the command substitution at the beginning of the input,
+// either $(...) or `...`, including nested substitutions and quoted parentheses
+func SubstitutionLength(input string) (int, error) {
+	if strings.HasPrefix(input, "`") {
+		for i := 1; i < len(input); i++ {
+			switch input[i] {
+			case '\\':
+				i++
+			case '`':
+				return i + 1, nil
+			}
+		}
+
+		return 0, errUnterminatedBackquote
+	}
+
+	inSingleQuote := false
+	inDoubleQuote := false
+	depth := 0
+
+	for i := 1; i < len(input); i++ {
+		char := input[i]
+
+		if !inSingleQuote && (char == '`' || strings.HasPrefix(input[i:], "$(")) {
+			length, err := SubstitutionLength(input[i:])
+			if err != nil {
+				return 0, err
+			}
+
+			i += length - 1
+			continue
+		}
+
+		switch {
+		case char == '\\' && !inSingleQuote:
+			i++
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		case inSingleQuote || inDoubleQuote:
+			continue
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+
+	return 0, errUnterminatedSubstitution
+}
+
+// ParameterLength returns the length of the parameter expansion at the beginning of the input, ${...},
+// including nested expansions and quoted braces. Inside double quotes, single quotes have no special meaning
+func ParameterLength(input string, doubleQuoted bool) (int, error) {
+	inSingleQuote := false
+	inDoubleQuote := false
+	depth := 0
+
+	for i := 2; i < len(input); i++ {
+		char := input[i]
+
+		if !inSingleQuote && (char == '`' || strings.HasPrefix(input[i:], "$(")) {
+			length, err := SubstitutionLength(input[i:])
+			if err != nil {
+				return 0, err
+			}
+
+			i += length - 1
+			continue
+		}
+
+		switch {
+		case char == '\\' && !inSingleQuote:
+			i++
+		case char == '\'' && !inDoubleQuote && !doubleQuoted:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		case inSingleQuote || inDoubleQuote:
+			continue
+		case char == '{':
+			depth++
+		case char == '}':
+			if depth == 0 {
+				return i + 1, nil
+			}
+			depth--
+		}
+	}
+
+	return 0, errUnterminatedParameter
+}
+
+// ArithmeticLength returns the length of the arithmetic expression at the beginning of the input, ((...)),
+// and whether the input starts with one. Input such as ((a) (b)) is not arithmetic, it does not end with ))
+func ArithmeticLength(input string) (int, bool) {
+	if !strings.HasPrefix(input, "((") {
+		return 0, false
+	}
+
+	inSingleQuote := false
+	inDoubleQuote := false
+	depth := 0
+
+	for i := 2; i < len(input); i++ {
+		char := input[i]
+
+		if !inSingleQuote && (char == '`' || strings.HasPrefix(input[i:], "$(")) {
+			length, err := SubstitutionLength(input[i:])
+			if err != nil {
+				return 0, false
+			}
+
+			i += length - 1
+			continue
+		}
+
+		switch {
+		case char == '\\' && !inSingleQuote:
+			i++
+		case char == '\'' && !inDoubleQuote:
+			inSingleQuote = !inSingleQuote
+		case char == '"' && !inSingleQuote:
+			inDoubleQuote = !inDoubleQuote
+		case inSingleQuote || inDoubleQuote:
+			continue
+		case char == '(':
+			depth++
+		case char == ')':
+			if depth > 0 {
+				depth--
+				continue
+			}
+
+			if i+1 < len(input) && input[i+1] == ')' {
+				return i + 2, true
+			}
+			return 0, false
+		}
+	}
+
+	return 0, false
+}
+
+// matchOperator returns the first of the operators the input starts with, or an empty string if there is none
+func matchOperator(input string, operators []string) string {
+	for _, operator := range operators {
+		if strings.HasPrefix(input, operator) {
+			return operator
+		}
+	}
+	return ""
+}
+
+func syntaxError(token string) error {
+	return fmt.Errorf("syntax error near unexpected token `%s'", token)
+}
+
+// unexpectedToken returns the syntax error for a token found where it cannot be, or ErrIncompleteInput at the end of the input
+func unexpectedToken(tok token) error {
+	switch tok.kind {
+	case tokenEOF:
+		return ErrIncompleteInput
+	case tokenNewline:
+		return syntaxError("newline")
+	}
+
+	return syntaxError(tok.text)
+}
+
+// parseStage parses a single pipeline stage, breaking it into raw tokens and collecting its redirections
+// Quotes and escape characters are kept in the tokens, they are removed by the executor when the tokens
+// are expanded, right before the command runs
+func (p *Parser) parseStage(input string) (types.PipelineStage, error) {
+	var stage types.PipelineStage
+	var currentToken strings.Builder
+
+	inSingleQuote := false
+	inDoubleQuote := false
+	escaping := false
+
+	// redirection is the redirection waiting for its target, which is the next token
+	var redirection *types.Redirection
+
+	// flushToken ends the current token, storing it as the redirection target if one is expected
+	flushToken := func() {
+		if currentToken.Len() == 0 {
+			return
+		}
+
+		token := currentToken.String()
+		currentToken.Reset()
+
+		if redirection != nil {
+			redirection.Target = token
+			stage.Redirections = append(stage.Redirections, *redirection)
+			redirection = nil
+			return
+		}
+
+		// Assignments are only recognized before the command name
+		if len(stage.Tokens) == 0 && stage.Arithmetic == "" && stage.Conditional == nil {
+			if assignment, isAssignment := parseAssignment(token); isAssignment {
+				stage.Assignments = append(stage.Assignments, assignment)
+				return
+			}
+		}
+
+		stage.Tokens = append(stage.Tokens, token)
+	}
+
+	for i := 0; i < len(input); i++ {
+		char := input[i]
+
+		if escaping {
+			currentToken.WriteByte(char)
+			escaping = false
+			continue
+		}
+
+		switch char {
+		case '\\':
+			currentToken.WriteByte(char)
+			escaping = !inSingleQuote
+		case '\'':
+			currentToken.WriteByte(char)
+			if !inDoubleQuote {
+				inSingleQuote = !inSingleQuote
+			}
+		case '"':
+			currentToken.WriteByte(char)
+			if !inSingleQuote {
+				inDoubleQuote = !inDoubleQuote
+			}
+		case ' ', '\t':
+			if inSingleQuote || inDoubleQuote {
+				currentToken.WriteByte(char) // Inside quotes, treat as literal
+				continue
+			}
+
+			flushToken() // Outside quotes, end of token
+		case '<', '>', '&':
+			operator := ""
+			if !inSingleQuote && !inDoubleQuote {
+				operator = matchOperator(input[i:], redirectionOperators)
+			}
+
+			if operator == "" {
+				currentToken.WriteByte(char)
+				continue
+			}
+
+			if redirection != nil {
+				return stage, errMissingRedirectTarget
+			}
+
+			fd := types.Stdout
+			if operator[0] == '<' {
+				fd = types.Stdin
+			}
+
+			// A number right before the operator selects the file descriptor (e.g. 2>)
+			if tokenFd, isFd := parseFd(currentToken.String()); isFd && operator[0] != '&' {
+				fd = tokenFd
+				currentToken.Reset()
+			} else {
+				flushToken()
+			}
+
+			redirection = &types.Redirection{Fd: fd, Operator: operator}
+
+			i += len(operator) - 1
+		case '(':
+			length, isArithmetic := 0, false
+			if !inSingleQuote && !inDoubleQuote && currentToken.Len() == 0 && len(stage.Tokens) == 0 && stage.Arithmetic == "" {
+				length, isArithmetic = ArithmeticLength(input[i:])
+			}
+
+			if !isArithmetic {
+				currentToken.WriteByte(char)
+				continue
+			}
+
+			// An arithmetic command ((...)) is kept whole, its expression is only expanded when it runs
+			stage.Arithmetic = strings.TrimSpace(input[i+2 : i+length-2])
+			if stage.Arithmetic == "" {
+				stage.Arithmetic = "0"
+			}
+			i += length - 1
+		case '[':
+			length, isConditional := 0, false
+			if !inSingleQuote && !inDoubleQuote && currentToken.Len() == 0 && len(stage.Tokens) == 0 && stage.Conditional == nil {
+				length, isConditional = ConditionalLength(input[i:])
+			}
+
+			if !isConditional {
+				currentToken.WriteByte(char)
+				continue
+			}
+
+			// A conditional command [[ ... ]] is kept as its raw words, which are only expanded as it evaluates them
+			stage.Conditional = splitConditional(input[i+2 : i+length-2])
+			if len(stage.Conditional) == 0 {
+				return stage, syntaxError("]]")
+			}
+			i += length - 1
+		case '$', '`':
+			if !inSingleQuote && (char == '`' || strings.HasPrefix(input[i:], "$(")) {
+				// Command substitutions are kept whole, they are parsed again when they run
+				length, err := SubstitutionLength(input[i:])
+				if err != nil {
+					return stage, err
+				}
+
+				currentToken.WriteString(input[i : i+length])
+				i += length - 1
+				continue
+			}
+
+			if char == '`' || inSingleQuote || !strings.HasPrefix(input[i:], "${") {
+				currentToken.WriteByte(char)
+				continue
+			}
+
+			// ${...} is kept whole, even if it contains spaces
+			length, err := ParameterLength(input[i:], inDoubleQuote)
+			if err != nil {
+				currentToken.WriteByte(char) // End not found, treat as literal
+				continue
+			}
+
+			currentToken.WriteString(input[i : i+length])
+			i += length - 1
+		default:
+			currentToken.WriteByte(char)
+		}
+	}
+
+	flushToken()
+
+	if inSingleQuote {
+		return stage, errUnterminatedSingleQuote
+	}
+	if inDoubleQuote {
+		return stage, errUnterminatedDoubleQuote
+	}
+	if redirection != nil {
+		return stage, errMissingRedirectTarget
+	}
+	if (stage.Arithmetic != "" || stage.Conditional != nil) && len(stage.Tokens) > 0 {
+		return stage, syntaxError(stage.Tokens[0])
+	}
+
+	return stage, nil
+}
+
+// parseAssignment parses a raw token of the form NAME=value, where NAME is unquoted and made of letters, digits and underscores
+func parseAssignment(token string) (types.Assignment, bool) {
+	name, value, found := strings.Cut(token, "=")
+	if !found || !IsValidName(name) {
+		return types.Assignment{}, false
+	}
+
+	return types.Assignment{Name: name, Value: value}, true
+}
+
+// IsValidName checks that a name can be used for a variable, starting with a letter or an underscore
+// followed by letters, digits and underscores
+func IsValidName(name string) bool {
+	if name == "" || isDigit(name[0]) {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		if !isDigit(name[i]) && name[i] != '_' && (name[i] < 'a' || name[i] > 'z') && (name[i] < 'A' || name[i] > 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseFd parses a raw token made only of digits as a file descriptor
+func parseFd(token string) (int, bool) {
+	if token == "" {
+		return 0, false
+	}
+
+	for i := 0; i < len(token); i++ {
+		if !isDigit(token[i]) {
+			return 0, false
+		}
+	}
+
+	fd, err := strconv.Atoi(token)
+	return fd, err == nil
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
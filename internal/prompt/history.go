@@ -1,8 +1,10 @@
 package prompt
 
 import (
+	"fmt"
 	"os"
-	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/utils"
 )
 
 func (p *Prompt) loadHistory() error {
@@ -14,11 +16,13 @@ func (p *Prompt) loadHistory() error {
 		return err
 	}
 
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			p.history = append(p.history, line)
+	entries := utils.SplitHistoryEntries(string(data))
+	p.history = append(p.history, entries...)
+
+	// The file saved by an older version is rewritten in the current format, before any entry is appended to it
+	if utils.IsLegacyHistory(string(data)) {
+		if err := p.rewriteHistoryFile(entries); err != nil {
+			return err
 		}
 	}
 
@@ -26,6 +30,33 @@ func (p *Prompt) loadHistory() error {
 	return nil
 }
 
+// addToHistory records a submitted input, replacing the history entry it was edited from, if any
+func (p *Prompt) addToHistory(input string, editedHistoryIndex int) {
+	if len(input) > 0 {
+		var writeErr error
+
+		if editedHistoryIndex >= 0 && editedHistoryIndex < len(p.history) {
+			p.history[editedHistoryIndex] = input
+			writeErr = p.appendToHistoryFile(input)
+		} else {
+			p.history = append(p.history, input)
+
+			if len(p.history) > p.cfg.MaxHistorySize {
+				p.history = p.history[len(p.history)-p.cfg.MaxHistorySize:]
+				writeErr = p.rewriteHistoryFile(p.history)
+			} else {
+				writeErr = p.appendToHistoryFile(input)
+			}
+		}
+
+		if writeErr != nil {
+			p.logger.Error(fmt.Sprintf("failed to append or write to history file: %v", writeErr))
+		}
+	}
+
+	p.historyIndex = len(p.history)
+}
+
 func (p *Prompt) appendToHistoryFile(cmd string) error {
 	f, err := os.OpenFile(p.cfg.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -33,7 +64,14 @@ func (p *Prompt) appendToHistoryFile(cmd string) error {
 	}
 	defer f.Close()
 
-	_, err = f.WriteString(cmd + "\n")
+	entry := utils.FormatHistoryEntry(cmd)
+
+	// A new file starts with the header telling the format of its entries
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		entry = utils.HistoryHeader + "\n" + entry
+	}
+
+	_, err = f.WriteString(entry)
 	return err
 }
 
@@ -44,8 +82,12 @@ func (p *Prompt) rewriteHistoryFile(history []string) error {
 	}
 	defer f.Close()
 
+	if _, err := f.WriteString(utils.HistoryHeader + "\n"); err != nil {
+		return err
+	}
+
 	for _, line := range history {
-		if _, err := f.WriteString(line + "\n"); err != nil {
+		if _, err := f.WriteString(utils.FormatHistoryEntry(line)); err != nil {
 			return err
 		}
 	}
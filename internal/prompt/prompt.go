@@ -5,25 +5,22 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/SebastianRichiteanu/Gosh/internal/autocompleter"
 	"github.com/SebastianRichiteanu/Gosh/internal/config"
 	"github.com/SebastianRichiteanu/Gosh/internal/logger"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
 	"github.com/SebastianRichiteanu/Gosh/internal/types"
 	"github.com/mattn/go-tty"
 )
 
-var (
-	errUnterminatedSingleQuote = errors.New("unterminated single quotes")
-	errUnterminatedDoubleQuote = errors.New("unterminated double quotes")
-)
-
 type Prompt struct {
 	cfg           *config.Config
 	builtinCmds   *types.CommandMap
 	autocompleter *autocompleter.Autocompleter
+	parser        *parser.Parser
 	logger        *logger.Logger
 
 	tty *tty.TTY
@@ -32,30 +29,43 @@ type Prompt struct {
 	runeChan      chan rune
 	errChan       chan error
 
-	history      []string
-	historyIndex int
+	// The terminal is only read while the prompt or a command of the shell waits for input, so that the jobs
+	// owning it in between get all that is typed on it (and Ctrl+C as their own SIGINT)
+	inputMu   sync.Mutex
+	inputCond *sync.Cond
+	listeners int
+
+	history            []string
+	historyIndex       int
+	editedHistoryIndex int // the history entry the last submitted line was edited from, -1 if there is none
+
+	linePrompt    string // the symbol of the prompt of the line being read
+	renderedLines int    // the number of lines the input spans below the prompt, as last rendered
 
 	aliases *types.Aliases
 }
 
-func NewPrompt(builtinCmds *types.CommandMap, autocompleter *autocompleter.Autocompleter, aliases *types.Aliases,
+func NewPrompt(builtinCmds *types.CommandMap, autocompleter *autocompleter.Autocompleter, parser *parser.Parser, aliases *types.Aliases,
 	cfg *config.Config, logger *logger.Logger) (*Prompt, error) {
 
 	p := Prompt{
 		cfg:           cfg,
 		builtinCmds:   builtinCmds,
 		autocompleter: autocompleter,
+		parser:        parser,
 		logger:        logger,
 
 		osSignalsChan: make(chan os.Signal, 1),
 		runeChan:      make(chan rune),
 		errChan:       make(chan error),
 
-		history:      []string{},
-		historyIndex: -1,
+		history:            []string{},
+		historyIndex:       -1,
+		editedHistoryIndex: -1,
 
 		aliases: aliases,
 	}
+	p.inputCond = sync.NewCond(&p.inputMu)
 
 	var err error
 
@@ -65,9 +75,6 @@ func NewPrompt(builtinCmds *types.CommandMap, autocompleter *autocompleter.Autoc
 		return nil, fmt.Errorf("failed to open tty: %v", err)
 	}
 
-	// listen for SIGINT (Ctrl+C)
-	signal.Notify(p.osSignalsChan, syscall.SIGINT)
-
 	if err := p.loadHistory(); err != nil {
 		return nil, fmt.Errorf("failed to load history: %v", err)
 	}
@@ -86,20 +93,80 @@ func (p *Prompt) Close() {
 	if p.tty != nil {
 		p.tty.Close()
 	}
+	signal.Stop(p.osSignalsChan)
 	close(p.osSignalsChan)
 	close(p.runeChan)
 	close(p.errChan)
 }
 
+// listen starts reading the terminal and catching SIGINT (Ctrl+C) for the caller waiting for input,
+// until the returned function is called
+func (p *Prompt) listen() func() {
+	p.inputMu.Lock()
+	defer p.inputMu.Unlock()
+
+	if p.listeners == 0 {
+		// A Ctrl+C caught before is stale, it was typed for a command which is now done
+		select {
+		case <-p.osSignalsChan:
+		default:
+		}
+		signal.Notify(p.osSignalsChan, syscall.SIGINT)
+	}
+
+	p.listeners++
+	p.inputCond.Broadcast()
+
+	return func() {
+		p.inputMu.Lock()
+		defer p.inputMu.Unlock()
+
+		p.listeners--
+		if p.listeners == 0 {
+			signal.Stop(p.osSignalsChan)
+		}
+	}
+}
+
 func (p *Prompt) HandlePrompt(previousInput string) (types.ParsedPrompt, string, error) {
 	fmt.Print(p.cfg.PromptSymbol + " " + previousInput)
 
-	input, skipExec := p.readInput(previousInput)
-	if skipExec {
+	input, result := p.readInput(p.cfg.PromptSymbol, previousInput)
+	switch result {
+	case readRedisplay:
 		return types.ParsedPrompt{}, input, nil
+	case readInterrupted:
+		return types.ParsedPrompt{}, "", nil
+	}
+
+	editedHistoryIndex := p.editedHistoryIndex
+
+	// Lines are read until the input is complete (e.g. up to the delimiter of a here-document) and recorded as one history entry
+	// Each line is parsed with the newline ending it, so that an escaped newline asks for the next line, as in a script
+	prompt, err := p.parser.Parse(input + "\n")
+	for errors.Is(err, parser.ErrIncompleteInput) {
+		line, interrupted := p.readContinuation()
+		if interrupted {
+			return types.ParsedPrompt{}, "", nil
+		}
+
+		input += "\n" + line
+		prompt, err = p.parser.Parse(input + "\n")
 	}
 
-	prompt, err := p.parseInput(strings.TrimSpace(input))
+	p.addToHistory(input, editedHistoryIndex)
 
 	return prompt, "", err
 }
+
+// readContinuation reads a line completing the previous ones, with the continuation prompt
+// It returns whether the reading was interrupted with Ctrl+C
+func (p *Prompt) readContinuation() (string, bool) {
+	line, result := "", readRedisplay
+	for result == readRedisplay {
+		fmt.Print(p.cfg.ContinuationSymbol + " " + line)
+		line, result = p.readInput(p.cfg.ContinuationSymbol, line)
+	}
+
+	return line, result == readInterrupted
+}
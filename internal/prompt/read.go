@@ -1,17 +1,23 @@
 package prompt
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/SebastianRichiteanu/Gosh/internal/builtins"
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
 	"github.com/SebastianRichiteanu/Gosh/internal/utils"
 )
 
+// inputPollInterval is how often the terminal is checked for input, as reading it must stop as soon as nobody listens
+const inputPollInterval = 100 * time.Millisecond
+
 func (p *Prompt) readRunes() {
 	for {
-		r, err := p.tty.ReadRune()
+		r, err := p.nextRune()
 		if err != nil {
 			p.errChan <- err
 			return
@@ -19,7 +25,7 @@ func (p *Prompt) readRunes() {
 
 		// If it's an escape character, read more to handle arrow keys
 		if r == runeEscape {
-			r2, err := p.tty.ReadRune()
+			r2, err := p.nextRune()
 			if err != nil {
 				p.errChan <- err
 				return
@@ -31,7 +37,7 @@ func (p *Prompt) readRunes() {
 				continue
 			}
 
-			r3, err := p.tty.ReadRune()
+			r3, err := p.nextRune()
 			if err != nil {
 				p.errChan <- err
 				return
@@ -60,23 +66,61 @@ func (p *Prompt) readRunes() {
 	}
 }
 
-func (p *Prompt) readInput(previousInput string) (string, bool) {
+// nextRune reads the next rune typed on the terminal, once someone listens for it and it is available
+// A read never blocks on the terminal, so a job given the terminal meanwhile does not have its input stolen
+func (p *Prompt) nextRune() (rune, error) {
+	for {
+		p.inputMu.Lock()
+		for p.listeners == 0 {
+			p.inputCond.Wait()
+		}
+		p.inputMu.Unlock()
+
+		if !p.tty.Buffered() {
+			ready, err := utils.WaitForInput(p.tty.Input(), inputPollInterval)
+			if err != nil {
+				return 0, err
+			}
+			if !ready {
+				continue
+			}
+		}
+
+		p.inputMu.Lock()
+		if p.listeners == 0 {
+			p.inputMu.Unlock()
+			continue
+		}
+		r, err := p.tty.ReadRune()
+		p.inputMu.Unlock()
+
+		return r, err
+	}
+}
+
+// readInput reads a line of input after the given prompt symbol, which was printed along with the previous input
+func (p *Prompt) readInput(linePrompt, previousInput string) (string, readResult) {
 	input := []rune(previousInput)
 	inputBkp := []rune{}
 	editedHistory := false // Track if the current history entry was edited
 
-	cursor := len(previousInput)
+	cursor := len(input)
 	pressedTab := false
 
+	p.linePrompt = linePrompt
+	p.renderedLines = 0
+
 	if p.historyIndex < 0 || p.historyIndex > len(p.history) {
 		p.historyIndex = len(p.history)
 	}
 
+	defer p.listen()()
+
 	for {
 		select {
 		case <-p.osSignalsChan:
 			fmt.Println("^C")
-			return "", false
+			return "", readInterrupted
 		case err := <-p.errChan:
 			p.logger.Error(fmt.Sprintf("read error for rune: %v", err))
 			continue
@@ -85,33 +129,18 @@ func (p *Prompt) readInput(previousInput string) (string, bool) {
 
 			switch char {
 			case runeCtrlL:
-				return builtins.BuiltinClear, false
+				return builtins.BuiltinClear, readSubmitted
 			case runeEnter:
 				fmt.Println()
-				if len(input) > 0 {
-					var writeErr error
-					cmd := string(input)
 
-					if editedHistory && p.historyIndex < len(p.history) {
-						p.history[p.historyIndex] = cmd
-						writeErr = p.appendToHistoryFile(cmd)
-					} else {
-						p.history = append(p.history, cmd)
-
-						if len(p.history) > p.cfg.MaxHistorySize {
-							p.history = p.history[len(p.history)-p.cfg.MaxHistorySize:]
-							writeErr = p.rewriteHistoryFile(p.history)
-						} else {
-							writeErr = p.appendToHistoryFile(cmd)
-						}
-					}
-
-					if writeErr != nil {
-						p.logger.Error(fmt.Sprintf("failed to append or write to history file: %v", writeErr))
-					}
+				// The line is added to the history by the caller, once the whole input is read
+				p.editedHistoryIndex = -1
+				if editedHistory && p.historyIndex < len(p.history) {
+					p.editedHistoryIndex = p.historyIndex
 				}
+
 				p.historyIndex = len(p.history)
-				return string(input), false
+				return string(input), readSubmitted
 			case runeBackspace:
 				if cursor > 0 {
 					input = append(input[:cursor-1], input[cursor:]...)
@@ -150,15 +179,21 @@ func (p *Prompt) readInput(previousInput string) (string, bool) {
 				// Handle autocompletion logic
 				inputAsStr := string(input)
 
-				currentPrompt, err := p.parseInput(inputAsStr)
-				if err != nil {
+				currentPrompt, err := p.parser.Parse(inputAsStr)
+				if err != nil && !errors.Is(err, parser.ErrIncompleteInput) {
 					p.logger.Error(fmt.Sprintf("failed to parse input: %v", err), "input", inputAsStr)
 					p.bell()
 					continue
 				}
 
-				tokenIndex := p.findTokenIndexAtPosition(currentPrompt.Tokens, cursor-1)
-				tokenToAutocomplete := currentPrompt.Tokens[tokenIndex]
+				tokens := promptTokens(currentPrompt)
+				if len(tokens) == 0 {
+					p.bell()
+					continue
+				}
+
+				tokenIndex := p.findTokenIndexAtPosition(tokens, cursor-1)
+				tokenToAutocomplete := tokens[tokenIndex]
 				suffixes := p.autocompleter.Autocomplete(*p.builtinCmds, tokenToAutocomplete)
 				if len(suffixes) == 0 {
 					p.bell()
@@ -224,7 +259,7 @@ func (p *Prompt) readInput(previousInput string) (string, bool) {
 				fmt.Fprintf(os.Stdout, "\r\n%s\n\r", strings.Join(suffixesWithInput, "  "))
 				pressedTab = false
 
-				return string(input), true // Return true so we don't execute
+				return string(input), readRedisplay // So we don't execute
 			case myRuneArrowUp:
 				if p.historyIndex > 0 {
 					if editedHistory && p.historyIndex < len(p.history) {
@@ -286,8 +321,8 @@ func (p *Prompt) readInput(previousInput string) (string, bool) {
 				input = append(input[:cursor], append([]rune{char}, input[cursor:]...)...)
 				cursor++
 				editedHistory = true
-				fmt.Printf("\r%s %s ", p.cfg.PromptSymbol, string(input))
-				fmt.Printf("\033[%dD", len(input)-cursor+1)
+				p.renderPrompt(append(input, ' '))
+				p.moveCursorBack(len(input) - cursor + 1)
 			}
 		}
 	}
@@ -0,0 +1,47 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+// ReadChar returns the next character typed on the terminal, for the commands of the shell reading from it such as read,
+// the prompt reading the terminal all along. Enter is returned as a newline and the arrow keys are skipped
+// It fails with os.ErrDeadlineExceeded once the timeout elapsed, if it is not 0, and with types.ErrInterrupted on Ctrl+C
+func (p *Prompt) ReadChar(timeout time.Duration) (rune, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	defer p.listen()()
+
+	for {
+		select {
+		case <-p.osSignalsChan:
+			fmt.Fprintln(p.tty.Output(), "^C")
+			return 0, types.ErrInterrupted
+		case err := <-p.errChan:
+			return 0, err
+		case <-expired:
+			return 0, os.ErrDeadlineExceeded
+		case char := <-p.runeChan:
+			switch {
+			case char == runeEnter:
+				return '\n', nil
+			case char >= 0:
+				return char, nil
+			}
+		}
+	}
+}
+
+// Echo writes text to the terminal, such as the characters typed for read, even if stdout is redirected
+func (p *Prompt) Echo(text string) {
+	fmt.Fprint(p.tty.Output(), text)
+}
@@ -20,3 +20,12 @@ const (
 	myRuneArrowLeft  = -1003 // Custom value for left arrow
 
 )
+
+// readResult tells how the reading of a line of input ended
+type readResult int
+
+const (
+	readSubmitted   readResult = iota // Enter was pressed
+	readRedisplay                     // the completions were listed, the input must be shown again without running it
+	readInterrupted                   // Ctrl+C was pressed, the input is dropped
+)
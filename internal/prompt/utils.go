@@ -3,10 +3,22 @@ package prompt
 import (
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
 )
 
+// renderPrompt redraws the prompt of the current line with the given input, which spans several lines
+// when it was recalled from a multi-line history entry (e.g. a here-document)
 func (p *Prompt) renderPrompt(prompt []rune) {
-	fmt.Printf("\r%s %s\033[K", p.cfg.PromptSymbol, string(prompt))
+	if p.renderedLines > 0 {
+		fmt.Printf("\033[%dA", p.renderedLines)
+	}
+
+	text := strings.ReplaceAll(string(prompt), "\n", "\n"+p.cfg.ContinuationSymbol+" ")
+	fmt.Printf("\r%s %s\033[J", p.linePrompt, text)
+
+	p.renderedLines = strings.Count(text, "\n")
 }
 
 func (p *Prompt) bell() {
@@ -28,3 +40,29 @@ func (p *Prompt) moveCursorFront(positions int) {
 
 	fmt.Printf("\033[%dC", positions)
 }
+
+func (p *Prompt) findTokenIndexAtPosition(tokens []string, position int) int {
+	index := 0
+	for idx, token := range tokens {
+		index += len(token)
+
+		if index >= position {
+			return idx
+		}
+	}
+
+	return len(tokens) - 1
+}
+
+// promptTokens flattens the tokens of all the stages of a parsed prompt
+func promptTokens(prompt types.ParsedPrompt) []string {
+	var tokens []string
+	for _, chain := range prompt.Chains {
+		for _, pipeline := range chain.Pipelines {
+			for _, stage := range pipeline.Stages {
+				tokens = append(tokens, stage.Tokens...)
+			}
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,98 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/parser"
+	"github.com/SebastianRichiteanu/Gosh/internal/types"
+)
+
+var errUnexpectedEOF = errors.New("syntax error: unexpected end of file")
+
+// Reader reads the commands of a non-interactive shell, from a script file, a -c string or stdin
+// The input is read one byte at a time, never past the end of the command being read, so that the commands
+// reading the same input (e.g. cat in a script piped to the shell) get the lines following them
+type Reader struct {
+	input  io.Reader
+	parser *parser.Parser
+
+	line int // the number of lines read so far
+	eof  bool
+}
+
+func NewReader(input io.Reader, parser *parser.Parser) *Reader {
+	return &Reader{
+		input:  input,
+		parser: parser,
+	}
+}
+
+// Next reads the lines up to the end of the next complete command, spanning several lines for compound commands
+// and here-documents, and returns the commands parsed from them
+// It returns io.EOF once the input is exhausted, and the syntax errors along with the line they were found on
+func (r *Reader) Next() (types.ParsedPrompt, error) {
+	var input strings.Builder
+
+	for {
+		if r.eof {
+			if strings.TrimSpace(input.String()) == "" {
+				return types.ParsedPrompt{}, io.EOF
+			}
+
+			// An escaped newline ending the input has no line to join, it is dropped
+			if rest, isEscaped := strings.CutSuffix(input.String(), "\\\n"); isEscaped {
+				if prompt, err := r.parser.Parse(rest); err == nil {
+					return prompt, nil
+				}
+			}
+			return types.ParsedPrompt{}, fmt.Errorf("line %d: %w", r.line, errUnexpectedEOF)
+		}
+
+		line, err := r.readLine()
+		if err != nil {
+			return types.ParsedPrompt{}, err
+		}
+		input.WriteString(line)
+
+		prompt, err := r.parser.Parse(input.String())
+		if errors.Is(err, parser.ErrIncompleteInput) {
+			continue
+		}
+		if err != nil {
+			return types.ParsedPrompt{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		return prompt, nil
+	}
+}
+
+// readLine reads the next line of the input, including its newline
+func (r *Reader) readLine() (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, 1)
+
+	for {
+		n, err := r.input.Read(buf)
+		if n == 1 {
+			sb.WriteByte(buf[0])
+			if buf[0] == '\n' {
+				r.line++
+				return sb.String(), nil
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			r.eof = true
+			if sb.Len() > 0 {
+				r.line++
+			}
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package traps
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Catchable are the signals the shell listens for, a trap replacing their default behavior
+var Catchable = []os.Signal{
+	syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM,
+	syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGALRM,
+}
+
+// signalName returns the name of a signal, e.g. SIGINT, or an empty string if there is no such signal
+func signalName(sig syscall.Signal) string {
+	return unix.SignalName(sig)
+}
+
+// signalNum returns the signal with the given name, e.g. SIGINT, or 0 if there is no such signal
+func signalNum(name string) syscall.Signal {
+	return unix.SignalNum(name)
+}
@@ -0,0 +1,42 @@
+package traps
+
+import (
+	"os"
+	"syscall"
+)
+
+// Catchable are the signals the shell listens for, a trap replacing their default behavior
+// Windows only delivers Ctrl+C, Ctrl+Break and the closing of the console, as SIGINT and SIGTERM
+var Catchable = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// signalNames are the signals the syscall package defines for Windows, which trap accepts
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGILL:  "SIGILL",
+	syscall.SIGTRAP: "SIGTRAP",
+	syscall.SIGABRT: "SIGABRT",
+	syscall.SIGBUS:  "SIGBUS",
+	syscall.SIGFPE:  "SIGFPE",
+	syscall.SIGKILL: "SIGKILL",
+	syscall.SIGSEGV: "SIGSEGV",
+	syscall.SIGPIPE: "SIGPIPE",
+	syscall.SIGALRM: "SIGALRM",
+	syscall.SIGTERM: "SIGTERM",
+}
+
+// signalName returns the name of a signal, e.g. SIGINT, or an empty string if there is no such signal
+func signalName(sig syscall.Signal) string {
+	return signalNames[sig]
+}
+
+// signalNum returns the signal with the given name, e.g. SIGINT, or 0 if there is no such signal
+func signalNum(name string) syscall.Signal {
+	for sig, sigName := range signalNames {
+		if sigName == name {
+			return sig
+		}
+	}
+	return 0
+}
@@ -0,0 +1,141 @@
+package traps
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Pseudo-signals, trapped like signals but raised by the shell itself
+const (
+	Exit   = "EXIT"   // the shell exits
+	Err    = "ERR"    // a command fails, in the cases where set -e would end the shell
+	Debug  = "DEBUG"  // a command is about to run
+	Return = "RETURN" // a function or a sourced file returns
+)
+
+// pseudoSignals are listed after the signals, EXIT excepted, in the order of this list
+var pseudoSignals = []string{Exit, Debug, Err, Return}
+
+// Table holds the commands trapped for signals and pseudo-signals, set with the trap builtin
+// Signals are received by the closer while the shell runs, their commands being pending until the executor runs them
+type Table struct {
+	mu       sync.Mutex
+	commands map[string]string // keyed by signal name, an empty command ignoring the signal
+	pending  []string          // the signals received whose commands did not run yet
+}
+
+func NewTable() *Table {
+	return &Table{
+		commands: make(map[string]string),
+	}
+}
+
+// Set traps a signal, running the command whenever it is received, or ignoring it if the command is empty
+func (t *Table) Set(name, command string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.commands[name] = command
+}
+
+// Reset removes the trap of a signal, restoring its default behavior
+func (t *Table) Reset(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.commands, name)
+}
+
+// Get returns the command trapped for a signal and whether the signal is trapped
+func (t *Table) Get(name string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	command, isTrapped := t.commands[name]
+	return command, isTrapped
+}
+
+// Names returns the names of the trapped signals, ordered as trap -p lists them
+func (t *Table) Names() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make([]string, 0, len(t.commands))
+	for name := range t.commands {
+		names = append(names, name)
+	}
+
+	slices.SortFunc(names, func(a, b string) int { return rank(a) - rank(b) })
+	return names
+}
+
+// Raise records that a trapped signal was received, its command running once the executor gets to it
+func (t *Table) Raise(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending = append(t.pending, name)
+}
+
+// TakePending returns the signals received since the last call, in the order they were received
+func (t *Table) TakePending() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := t.pending
+	t.pending = nil
+	return pending
+}
+
+// ParseSignal returns the name of a signal given by name, with or without its SIG prefix, or by number
+// 0 is EXIT, as are the pseudo-signals, whose names are returned as is
+func ParseSignal(spec string) (string, error) {
+	errInvalid := fmt.Errorf("%s: invalid signal specification", spec)
+	upper := strings.ToUpper(spec)
+
+	switch {
+	case upper == "0":
+		return Exit, nil
+	case slices.Contains(pseudoSignals, upper):
+		return upper, nil
+	}
+
+	if number, err := strconv.Atoi(spec); err == nil {
+		if name := signalName(syscall.Signal(number)); name != "" {
+			return name, nil
+		}
+		return "", errInvalid
+	}
+
+	if !strings.HasPrefix(upper, "SIG") {
+		upper = "SIG" + upper
+	}
+
+	if signalNum(upper) == 0 {
+		return "", errInvalid
+	}
+
+	return upper, nil
+}
+
+// SignalName returns the name of a received signal, as trapped
+func SignalName(sig os.Signal) string {
+	if s, isSyscall := sig.(syscall.Signal); isSyscall {
+		return signalName(s)
+	}
+	return sig.String()
+}
+
+// rank orders EXIT first, then the signals by number, then the other pseudo-signals
+func rank(name string) int {
+	if idx := slices.Index(pseudoSignals, name); idx != -1 && name != Exit {
+		return 1000 + idx
+	}
+
+	return int(signalNum(name)) // 0 for EXIT
+}
@@ -1,27 +1,282 @@
 package types
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/variables"
+)
+
+const (
+	Stdin = iota
+	Stdout
+	Stderr
+)
+
 const (
-	Stdout = iota + 1
-	Stderr = iota + 1
+	OperatorAnd = "&&"
+	OperatorOr  = "||"
+)
+
+// Redirection operators, the ones starting with < apply to stdin by default and the others to stdout
+const (
+	RedirectInput      = "<"
+	RedirectReadWrite  = "<>"
+	RedirectDupInput   = "<&"
+	RedirectHereString = "<<<"
+	RedirectHereDoc    = "<<"
+	RedirectHereDocTab = "<<-"
+	RedirectOutput     = ">"
+	RedirectAppend     = ">>"
+	RedirectClobber    = ">|"
+	RedirectDupOutput  = ">&"
+	RedirectOutputAll  = "&>"
+	RedirectAppendAll  = "&>>"
 )
 
-const DefaultStdStream = Stdout
 const PathDelimiter = ":"
 const PathEnvVar = "PATH"
 
-// Command represents the type for built-in or external commands
-type Command interface{}
+// Builtin is a command run by the shell itself, rather than as a child process
+type Builtin interface {
+	// Usage declares the arguments the builtin takes, which the executor checks before running it
+	Usage() Usage
 
-// CommandMap is a map that stores known commands keyed by their name
-type CommandMap map[string]Command
+	// Run runs the builtin with the arguments following its name, wired to the given streams, and returns its exit status
+	Run(ctx context.Context, args []string, stdio Stdio, shell Shell) int
+}
 
-// ParsedPrompt is a structure that holds the tokens from user input, stream redirection details, and other command-related information
-type ParsedPrompt struct {
+// UnlimitedArgs is the maximum number of arguments of a builtin taking any number of them
+const UnlimitedArgs = -1
+
+// Usage declares how a builtin is called
+type Usage struct {
+	Synopsis string // e.g. "cd [dir]", printed when it gets too few arguments
+	MinArgs  int
+	MaxArgs  int // UnlimitedArgs if there is no maximum
+}
+
+// Check checks the number of arguments given to a builtin against its usage, returning the error to report otherwise
+// Too few arguments print the synopsis and give the exit status 2, too many give 1
+func (u Usage) Check(name string, args []string) *StatusError {
+	if len(args) < u.MinArgs {
+		return &StatusError{Status: 2, Err: fmt.Errorf("%s: usage: %s", name, u.Synopsis)}
+	}
+
+	if u.MaxArgs != UnlimitedArgs && len(args) > u.MaxArgs {
+		return &StatusError{Status: 1, Err: fmt.Errorf("%s: too many arguments", name)}
+	}
+
+	return nil
+}
+
+// Stdio holds the streams a builtin reads from and writes to, which are the ones of the shell unless piped or redirected
+type Stdio struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Shell is the state of the shell, or of the subshell, a builtin runs in
+type Shell interface {
+	Variables() *variables.Store
+	LastStatus() int
+	IsSubshell() bool
+	Interrupt() // stops the rest of the line, as when Ctrl+C kills a foreground job
+
+	PositionalArgs() []string // $1 and the following ones, which shift and set -- change
+	SetPositionalArgs(args []string)
+
+	LoopDepth() int // the number of loops running, which break and continue leave
+	// LeaveLoops leaves the given number of the loops running, going on with the next iteration of the last one if next is set
+	LeaveLoops(levels int, next bool)
+
+	CanReturn() bool // whether a function or a sourced file runs, which return leaves
+	Return()
+
+	// Source runs the commands of a file in the shell itself, wired to its streams, and returns the exit status of the last one
+	// The arguments become the positional parameters while it runs, if there are any
+	Source(path string, args []string) int
+
+	// Exec replaces the shell with the command, wired to its streams, and returns the exit status of the command if it cannot
+	Exec(args []string) int
+
+	// Read reads a line of input and assigns its fields to the variables of the shell, returning the exit status of read
+	Read(args []string, stdio Stdio) int
+
+	Dir() (string, error) // the working directory, which a subshell keeps on its own
+	Chdir(dir string) error
+	ResolvePath(path string) string // the path of a file relative to the working directory, as the system calls must get it
+}
+
+// CommandMap is a map that stores the builtins keyed by their name
+type CommandMap map[string]Builtin
+
+// Redirection is a single redirection of a command, such as `2>>errors.log`, `2>&1` or `<<<word`
+type Redirection struct {
+	Fd       int // the file descriptor being redirected
+	Operator string
+	Target   string // the raw word following the operator: a file name, a file descriptor (or - to close it), a here-string or the delimiter of a here-document
+	Document string // the body of a here-document, read from the lines following the command
+}
+
+// Assignment is a variable assignment word, NAME=value, preceding a command or standing on its own
+type Assignment struct {
+	Name  string
+	Value string // the raw value, expanded right before the command runs
+}
+
+// PipelineStage is a single command of a pipeline, holding its tokens and its redirections, applied in order
+// The assignments preceding the command only apply to its environment, without a command they set shell variables
+type PipelineStage struct {
+	Assignments  []Assignment
 	Tokens       []string
-	StdStream    int
-	RedirectFile string
-	Truncate     bool
+	Redirections []Redirection
+
+	// Arithmetic is the expression of an arithmetic command ((...)), which has no tokens
+	// An empty expression is stored as 0, so that it still marks the stage as an arithmetic command
+	Arithmetic string
+
+	// Conditional holds the raw words and operators of a conditional command [[ ... ]], which has no tokens
+	Conditional []string
+
+	// Compound is the control flow command of the stage, which has no tokens, its redirections applying to the whole command
+	Compound *CompoundCommand
+
+	// Function is the function the stage defines, which has no tokens
+	Function *FunctionDefinition
+}
+
+// Terminators of the items of a case command
+const (
+	CaseBreak       = ";;"  // stops after the body of the item
+	CaseFallthrough = ";&"  // runs the body of the next item as well
+	CaseContinue    = ";;&" // goes on testing the patterns of the next items
+)
+
+// CompoundCommand is a control flow command, run by the shell itself, of which exactly one clause is set
+type CompoundCommand struct {
+	Group         *ParsedPrompt // { list; }, running its commands in the shell itself
+	If            *IfClause
+	While         *WhileClause
+	For           *ForClause
+	ArithmeticFor *ArithmeticForClause
+	Case          *CaseClause
+}
+
+// IfClause is if/elif/else, Bodies[i] running when Conditions[i] is the first condition to succeed
+// Else runs when none of them succeeds, if there is one
+type IfClause struct {
+	Conditions []ParsedPrompt
+	Bodies     []ParsedPrompt
+	Else       *ParsedPrompt
+}
+
+// WhileClause is a while loop, running its body as long as its condition succeeds, or an until loop as long as it fails
+type WhileClause struct {
+	Until     bool
+	Condition ParsedPrompt
+	Body      ParsedPrompt
+}
+
+// ForClause is a for loop, running its body with the variable set to each field the words expand to
+// Without `in`, the words are "$@", looping over the positional parameters
+type ForClause struct {
+	Name  string
+	Words []string
+	Body  ParsedPrompt
+}
+
+// ArithmeticForClause is a C-style for loop, for ((init; condition; update)), whose expressions are kept raw
+// An empty condition is always true
+type ArithmeticForClause struct {
+	Init      string
+	Condition string
+	Update    string
+	Body      ParsedPrompt
+}
+
+// CaseClause is a case command, running the body of the first item with a pattern matching its word
+type CaseClause struct {
+	Word  string
+	Items []CaseItem
+}
+
+// CaseItem is an item of a case command, its patterns being raw words separated by | in the input
+type CaseItem struct {
+	Patterns   []string
+	Body       ParsedPrompt
+	Terminator string // one of CaseBreak, CaseFallthrough and CaseContinue
+}
+
+// FunctionDefinition is a shell function, defined as `name() compound-command` or `function name compound-command`
+type FunctionDefinition struct {
+	Name string
+	Body PipelineStage // a compound command, its redirections being applied every time the function runs
+	Text string        // the definition as typed, shown by type
+}
+
+// Functions is a map that stores the shell functions, where the key is the function name
+type Functions map[string]FunctionDefinition
+
+// Pipeline is a sequence of stages, the stdout of each stage being connected to the stdin of the next one
+type Pipeline struct {
+	Stages []PipelineStage
+	Text   string
+}
+
+// Chain is a sequence of pipelines joined by && or || operators, where Operators[i] joins Pipelines[i] and Pipelines[i+1]
+// Each pipeline runs depending on the exit status of the previous one
+// A chain terminated by & runs in the background as a job
+type Chain struct {
+	Pipelines  []Pipeline
+	Operators  []string
+	Background bool
+	Text       string
+}
+
+// ParsedPrompt is a structure that holds the chains parsed from user input, which were separated by ;, & or newlines
+// It is also the list of commands making up the bodies of compound commands
+type ParsedPrompt struct {
+	Chains []Chain
+}
+
+// Options holds the shell options toggled with the set and shopt builtins
+type Options struct {
+	Noclobber bool // set -C, > does not overwrite existing files
+	Errexit   bool // set -e, the shell exits as soon as a command fails, unless it is tested by a condition, && or ||
+	Xtrace    bool // set -x, the commands are printed to stderr once expanded, right before they run, after $PS4
+	Nounset   bool // set -u, expanding an unset parameter is an error
+	Pipefail  bool // set -o pipefail, the exit status of a pipeline is the one of its last failing command
+	Noglob    bool // set -f, no pathname expansion
+
+	Nullglob bool // shopt -s nullglob, a pattern matching no file expands to nothing
+	Failglob bool // shopt -s failglob, a pattern matching no file is an error
+	Dotglob  bool // shopt -s dotglob, wildcards match names starting with a dot
 }
 
 // Aliases is a map that stores aliases for commands, where the key is the alias name and the value is the command it represents
 type Aliases map[string]string
+
+// ErrInterrupted is returned when reading the input typed on the terminal is interrupted with Ctrl+C
+var ErrInterrupted = errors.New("interrupted")
+
+// StatusError is returned by builtins to report a specific exit status
+// Nothing is printed for it unless Err is set
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Status)
+	}
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
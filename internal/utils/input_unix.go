@@ -0,0 +1,25 @@
+//go:build unix
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// WaitForInput waits until there is input to read from the file or the timeout elapses, telling whether there is some
+func WaitForInput(file *os.File, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	fds := []unix.PollFd{{Fd: int32(file.Fd()), Events: unix.POLLIN}}
+
+	for {
+		n, err := unix.Poll(fds, int(max(time.Until(deadline), 0).Milliseconds()))
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		return n > 0, err
+	}
+}
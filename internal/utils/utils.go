@@ -3,21 +3,20 @@ package utils
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/SebastianRichiteanu/Gosh/internal/types"
 )
 
-// FindPath searches for the given command in the system's PATH, or checks if it's a local or full path
+// FindPath searches for the given command in the directories of the given PATH, or checks if it's a local or full path
 // It returns the full file path if found or an empty string if not
-func FindPath(cmd string) string {
+func FindPath(cmd, path string) string {
 	if len(cmd) == 0 {
 		return ""
 	}
@@ -40,9 +39,8 @@ func FindPath(cmd string) string {
 			return cmd
 		}
 	default: // Search in PATH
-		paths := strings.Split(os.Getenv(types.PathEnvVar), types.PathDelimiter)
-		for _, path := range paths {
-			fp := filepath.Join(path, cmd)
+		for _, dir := range strings.Split(path, types.PathDelimiter) {
+			fp := filepath.Join(dir, cmd)
 			if _, err := os.Stat(fp); err == nil {
 				return fp
 			}
@@ -95,18 +93,103 @@ func OpenFileForStdout(filePath string, truncate bool) (*os.File, error) {
 	return os.OpenFile(filePath, flag, 0644)
 }
 
-// GetStdStream parses the redirection operator (e.g., `1>` or `2>`) and returns the corresponding stream type (stdout or stderr)
-func GetStdStream(input string, pos int) (int, error) {
-	if pos >= len(input) {
-		return types.DefaultStdStream, nil
+// HistoryHeader is the first line of a history file whose entries are saved by FormatHistoryEntry
+// The files without it were saved by older versions, in which any line ending with a backslash continues the entry,
+// they are read that way and rewritten with the header, see IsLegacyHistory
+const HistoryHeader = "#gosh history v2"
+
+// IsLegacyHistory checks whether the content of a history file was saved by an older version, without the header
+func IsLegacyHistory(data string) bool {
+	return data != "" && !strings.HasPrefix(data, HistoryHeader+"\n")
+}
+
+// SplitHistoryEntries splits the content of a history file into its entries
+// A multi-line entry (e.g. a command with a here-document) is saved with a backslash ending all its lines but the last one,
+// the backslashes which really end a line being doubled so they are told apart, see FormatHistoryEntry
+func SplitHistoryEntries(data string) []string {
+	var entries []string
+
+	ScanHistoryEntries(strings.NewReader(data), func(entry string) error {
+		entries = append(entries, entry)
+		return nil
+	})
+
+	return entries
+}
+
+// ScanHistoryEntries reads the entries of a history file one at a time, passing each one to yield as soon as it is read
+// The files saved by older versions, without the header, are read as they were saved
+// It stops at the first error returned by yield or by the reader
+func ScanHistoryEntries(r io.Reader, yield func(entry string) error) error {
+	reader := bufio.NewReader(r)
+	var pending strings.Builder
+
+	isFirstLine, isLegacy := true, true
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		if isFirstLine {
+			isFirstLine = false
+
+			if line == HistoryHeader {
+				isLegacy = false
+				if readErr == io.EOF {
+					return nil
+				}
+				continue
+			}
+		}
+
+		// An odd number of trailing backslashes continues the entry on the next line, or any number in a legacy file
+		content := strings.TrimRight(line, "\\")
+		backslashes := len(line) - len(content)
+
+		continues := backslashes%2 == 1
+		if isLegacy {
+			line, continues = strings.CutSuffix(line, "\\")
+		} else {
+			line = content + strings.Repeat("\\", backslashes/2)
+		}
+
+		if continues {
+			pending.WriteString(line + "\n")
+		} else {
+			entry := strings.TrimSpace(pending.String() + line)
+			pending.Reset()
+
+			if entry != "" {
+				if err := yield(entry); err != nil {
+					return err
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
 	}
+}
+
+// FormatHistoryEntry returns an entry the way it is saved in the history file, see SplitHistoryEntries
+// The backslashes ending its lines are doubled, and the lines but the last one get one more marking the continuation
+func FormatHistoryEntry(entry string) string {
+	lines := strings.Split(entry, "\n")
+
+	for idx, line := range lines {
+		content := strings.TrimRight(line, "\\")
+		lines[idx] = line + strings.Repeat("\\", len(line)-len(content))
 
-	if input[pos] == ' ' {
-		// > or >> are treated as 1> or 1>>
-		return types.DefaultStdStream, nil
+		if idx < len(lines)-1 {
+			lines[idx] += "\\"
+		}
 	}
 
-	return strconv.Atoi(string(input[pos]))
+	return strings.Join(lines, "\n") + "\n"
 }
 
 // BlockCtrlC will start a channel and will listen for OS signals
@@ -147,65 +230,6 @@ func FindLongestPrefix(cmds []string) string {
 	return common
 }
 
-func HandleExportLine(line string) {
-	line = strings.TrimPrefix(line, "export")
-	line = strings.Trim(line, " ")
-
-	parts := strings.SplitN(line, "=", 2)
-
-	if len(parts) != 2 {
-		return
-	}
-
-	key := strings.TrimSpace(parts[0])
-	value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
-
-	os.Setenv(key, value)
-}
-
-func SourceFile(filePath string) error {
-	filePath, err := ExpandHomePath(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to expand home path: %w", err)
-	}
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Check if it's a variable assignment (e.g., VAR=value)
-		if strings.Contains(line, "=") {
-			HandleExportLine(line)
-			continue
-		}
-
-		// Execute command if not
-		cmd := exec.Command("sh", "-c", line)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
-		if err != nil {
-			fmt.Println("Error executing command:", line, err)
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("Error reading %s: %w", filePath, err)
-	}
-
-	return nil
-}
-
 func ExitShell(exitCode int) {
 	//logger.Close()
 	// Maybe create chan that waits for signals for stuff that I want to execute before exit
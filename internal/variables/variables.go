@@ -0,0 +1,384 @@
+package variables
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SebastianRichiteanu/Gosh/internal/arithmetic"
+)
+
+var (
+	ErrReadonly   = errors.New("readonly variable")
+	ErrNotInScope = errors.New("can only be used in a function")
+)
+
+// Variable is a shell variable along with its attributes
+type Variable struct {
+	Value    string
+	IsSet    bool // a variable can be declared with attributes but no value (e.g. export NAME)
+	Exported bool // passed to the environment of child processes
+	Readonly bool // cannot be assigned or unset
+	Integer  bool // assigned values are evaluated as arithmetic expressions
+
+	// Elements are the elements of an indexed array, such as BASH_REMATCH, Value being the first one. nil for a scalar
+	Elements []string
+}
+
+// scope maps the names of variables to the variables of a scope
+type scope map[string]*Variable
+
+// Store holds the shell variables, separate from the environment of the process
+// Functions push scopes holding their local variables, a name resolving to the variable of the innermost scope defining it
+// The store of the shell itself mirrors its exported variables into the environment of the process, so that the code
+// reading the environment (e.g. the PATH lookup) sees them
+type Store struct {
+	mu        sync.RWMutex
+	scopes    []scope // the global scope comes first
+	mirrorEnv bool
+}
+
+// NewStore returns a store holding the variables of the process environment, all of them exported
+func NewStore() *Store {
+	globals := make(scope)
+	for _, entry := range os.Environ() {
+		if name, value, found := strings.Cut(entry, "="); found {
+			globals[name] = &Variable{Value: value, IsSet: true, Exported: true}
+		}
+	}
+
+	return &Store{
+		scopes:    []scope{globals},
+		mirrorEnv: true,
+	}
+}
+
+// Clone returns a copy of the store, used by subshells whose assignments are not seen by the shell
+// The copy never changes the environment of the process
+func (s *Store) Clone() *Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scopes := make([]scope, len(s.scopes))
+	for idx, sc := range s.scopes {
+		scopes[idx] = make(scope, len(sc))
+		for name, v := range sc {
+			copied := *v
+			copied.Elements = slices.Clone(v.Elements)
+			scopes[idx][name] = &copied
+		}
+	}
+
+	return &Store{scopes: scopes}
+}
+
+// Lookup returns the value of a variable and whether it is set
+func (s *Store) Lookup(name string) (string, bool) {
+	v, found := s.Get(name)
+	return v.Value, found && v.IsSet
+}
+
+// Get returns a copy of a variable, including its attributes, and whether it exists
+func (s *Store) Get(name string) (Variable, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if v := s.find(name); v != nil {
+		copied := *v
+		copied.Elements = slices.Clone(v.Elements)
+		return copied, true
+	}
+	return Variable{}, false
+}
+
+// Set assigns a value to a variable, keeping its attributes, or creates a global variable
+// A variable with the integer attribute gets the value of the value evaluated as an arithmetic expression
+func (s *Store) Set(name, value string) error {
+	if v, found := s.Get(name); found && v.Integer {
+		result, err := arithmetic.Evaluate(value, s)
+		if err != nil {
+			return err
+		}
+		value = strconv.FormatInt(result, 10)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.find(name)
+	if v == nil {
+		v = &Variable{}
+		s.scopes[0][name] = v
+	}
+
+	if v.Readonly {
+		return fmt.Errorf("%s: %w", name, ErrReadonly)
+	}
+
+	v.Value = value
+	v.IsSet = true
+	if len(v.Elements) > 0 {
+		v.Elements[0] = value // Assigning an array without a subscript assigns its first element
+	}
+	s.syncEnv(name)
+
+	return nil
+}
+
+// SetArray assigns the elements of an indexed array to a variable, keeping its attributes, or creates a global array
+func (s *Store) SetArray(name string, elements []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.find(name)
+	if v == nil {
+		v = &Variable{}
+		s.scopes[0][name] = v
+	}
+
+	if v.Readonly {
+		return fmt.Errorf("%s: %w", name, ErrReadonly)
+	}
+
+	v.Elements = append([]string{}, elements...)
+	v.Value = ""
+	if len(elements) > 0 {
+		v.Value = elements[0]
+	}
+	v.IsSet = true
+	s.syncEnv(name)
+
+	return nil
+}
+
+// Unset removes a variable from the innermost scope defining it
+func (s *Store) Unset(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for idx := len(s.scopes) - 1; idx >= 0; idx-- {
+		v, found := s.scopes[idx][name]
+		if !found {
+			continue
+		}
+
+		if v.Readonly {
+			return fmt.Errorf("%s: cannot unset: %w", name, ErrReadonly)
+		}
+
+		delete(s.scopes[idx], name)
+		s.syncEnv(name)
+		return nil
+	}
+
+	return nil
+}
+
+// SetAttributes changes the attributes of a variable, creating it without a value if it does not exist
+// The attributes to change are the non nil ones, readonly can only be turned on
+func (s *Store) SetAttributes(name string, exported, readonly, integer *bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.find(name)
+	if v == nil {
+		v = &Variable{}
+		s.scopes[0][name] = v
+	}
+
+	if readonly != nil {
+		if v.Readonly && !*readonly {
+			return fmt.Errorf("%s: %w", name, ErrReadonly)
+		}
+		v.Readonly = *readonly
+	}
+	if exported != nil {
+		v.Exported = *exported
+	}
+	if integer != nil {
+		v.Integer = *integer
+	}
+
+	s.syncEnv(name)
+	return nil
+}
+
+// Restore puts back a variable as returned by Get, including its attributes, or removes it if it did not exist
+// It is used to undo a temporary assignment, so it ignores the readonly attribute
+func (s *Store) Restore(name string, v Variable, existed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existed {
+		if current := s.find(name); current != nil {
+			*current = v
+		} else {
+			s.scopes[0][name] = &v
+		}
+	} else {
+		for idx := len(s.scopes) - 1; idx >= 0; idx-- {
+			if _, found := s.scopes[idx][name]; found {
+				delete(s.scopes[idx], name)
+				break
+			}
+		}
+	}
+
+	s.syncEnv(name)
+}
+
+// PushScope starts a new scope for the local variables of a function
+func (s *Store) PushScope() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scopes = append(s.scopes, make(scope))
+}
+
+// PopScope ends the innermost scope, dropping its local variables
+func (s *Store) PopScope() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.scopes) == 1 {
+		return
+	}
+
+	popped := s.scopes[len(s.scopes)-1]
+	s.scopes = s.scopes[:len(s.scopes)-1]
+
+	for name := range popped {
+		s.syncEnv(name)
+	}
+}
+
+// Local declares a variable in the innermost scope, hiding the variables with the same name in the outer scopes
+// It fails outside of a function, where there is only the global scope
+func (s *Store) Local(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.scopes) == 1 {
+		return ErrNotInScope
+	}
+
+	innermost := s.scopes[len(s.scopes)-1]
+	if _, found := innermost[name]; !found {
+		innermost[name] = &Variable{}
+	}
+
+	return nil
+}
+
+// Names returns the sorted names of all the visible variables
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var names []string
+
+	for _, sc := range s.scopes {
+		for name := range sc {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Environ returns the exported variables which are set, in the NAME=value form of the environment of a process
+func (s *Store) Environ() []string {
+	var env []string
+
+	for _, name := range s.Names() {
+		if v, _ := s.Get(name); v.Exported && v.IsSet {
+			env = append(env, name+"="+v.Value)
+		}
+	}
+
+	return env
+}
+
+// find returns the variable visible under a name, from the innermost scope defining it
+func (s *Store) find(name string) *Variable {
+	for idx := len(s.scopes) - 1; idx >= 0; idx-- {
+		if v, found := s.scopes[idx][name]; found {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// syncEnv updates the environment of the process after a change to a variable, if the store mirrors it
+func (s *Store) syncEnv(name string) {
+	if !s.mirrorEnv {
+		return
+	}
+
+	if v := s.find(name); v != nil && v.Exported && v.IsSet {
+		os.Setenv(name, v.Value)
+	} else {
+		os.Unsetenv(name)
+	}
+}
+
+// Declaration returns the declare command recreating a variable with its attributes, as listed by declare -p
+func Declaration(name string, v Variable) string {
+	flags := ""
+	if v.Elements != nil {
+		flags += "a"
+	}
+	if v.Integer {
+		flags += "i"
+	}
+	if v.Readonly {
+		flags += "r"
+	}
+	if v.Exported {
+		flags += "x"
+	}
+	if flags == "" {
+		flags = "-"
+	}
+
+	if !v.IsSet {
+		return fmt.Sprintf("declare -%s %s", flags, name)
+	}
+
+	if v.Elements != nil {
+		elements := make([]string, len(v.Elements))
+		for idx, element := range v.Elements {
+			elements[idx] = fmt.Sprintf("[%d]=%s", idx, Quote(element))
+		}
+		return fmt.Sprintf("declare -%s %s=(%s)", flags, name, strings.Join(elements, " "))
+	}
+
+	return fmt.Sprintf("declare -%s %s=%s", flags, name, Quote(v.Value))
+}
+
+// Quote double-quotes a value so that the shell reads it back as is
+func Quote(value string) string {
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+	for _, r := range value {
+		if strings.ContainsRune("\"\\$`", r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
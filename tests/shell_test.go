@@ -187,6 +187,357 @@ func TestShell(t *testing.T) {
 			want:    []string{"", "", "ls: cannot access 'non_existing_file': No such file or directory\r\nls: cannot access 'another_missing_file': No such file or directory"},
 			wantErr: false,
 		},
+		{
+			name:    "test stderr duplicated to stdout",
+			input:   []string{"ls non_existing_file 2>&1 | tr a-z A-Z"},
+			want:    []string{"LS: CANNOT ACCESS 'NON_EXISTING_FILE': NO SUCH FILE OR DIRECTORY"},
+			wantErr: false,
+		},
+		{
+			name:    "test input redirection",
+			input:   []string{"echo Hello, Gosh! > ./tmp/input.txt; tr a-z A-Z < ./tmp/input.txt"},
+			want:    []string{"HELLO, GOSH!"},
+			wantErr: false,
+		},
+		{
+			name:    "test here-string",
+			input:   []string{`tr a-z A-Z <<< "Hello, Gosh!"`},
+			want:    []string{"HELLO, GOSH!"},
+			wantErr: false,
+		},
+		{
+			name:    "test noclobber",
+			input:   []string{"rm -f ./tmp/noclobber.txt; set -C; echo a > ./tmp/noclobber.txt; echo b > ./tmp/noclobber.txt; echo c >| ./tmp/noclobber.txt; cat ./tmp/noclobber.txt"},
+			want:    []string{"gosh: ./tmp/noclobber.txt: cannot overwrite existing file\r\nc"},
+			wantErr: false,
+		},
+
+		{
+			name:    "test pipeline between binaries",
+			input:   []string{"echo Hello, Gosh! | tr a-z A-Z"},
+			want:    []string{"HELLO, GOSH!"},
+			wantErr: false,
+		},
+		{
+			name:    "test pipeline from builtin",
+			input:   []string{"type echo | cat | cat"},
+			want:    []string{"echo is a shell builtin"},
+			wantErr: false,
+		},
+		{
+			name:    "test pipeline with quoted pipe",
+			input:   []string{"echo 'a|b' | cat"},
+			want:    []string{"a|b"},
+			wantErr: false,
+		},
+		{
+			name:    "test pipeline closed early",
+			input:   []string{"yes | head -2"},
+			want:    []string{"y\r\ny"},
+			wantErr: false,
+		},
+
+		{
+			name:    "test command list",
+			input:   []string{"echo first; echo second"},
+			want:    []string{"first\r\nsecond"},
+			wantErr: false,
+		},
+		{
+			name:    "test and list stops on failure",
+			input:   []string{"false && echo skipped; echo done"},
+			want:    []string{"done"},
+			wantErr: false,
+		},
+		{
+			name:    "test or list runs on failure",
+			input:   []string{"ls2 || echo fallback"},
+			want:    []string{"ls2: not found\r\nfallback"},
+			wantErr: false,
+		},
+		{
+			name:    "test builtin error status",
+			input:   []string{"cd /non_existing_dir || echo fallback"},
+			want:    []string{"cd: /non_existing_dir: No such file or directory\r\nfallback"},
+			wantErr: false,
+		},
+
+		{
+			name:    "test exit status of failed command",
+			input:   []string{"false; echo $?"},
+			want:    []string{"1"},
+			wantErr: false,
+		},
+		{
+			name:    "test exit status of command not found",
+			input:   []string{"ls2; echo $?"},
+			want:    []string{"ls2: not found\r\n127"},
+			wantErr: false,
+		},
+		{
+			name:    "test exit status of command killed by signal",
+			input:   []string{`sh -c 'kill -9 $$'; echo $?`},
+			want:    []string{"Killed\r\n137"},
+			wantErr: false,
+		},
+		{
+			name:    "test variable expansion within double quotes",
+			input:   []string{`echo "$HOME"`},
+			want:    []string{homeDir},
+			wantErr: false,
+		},
+		{
+			name:    "test foreground without jobs",
+			input:   []string{"jobs; fg || echo failed"},
+			want:    []string{"fg: current: no current job\r\nfailed"},
+			wantErr: false,
+		},
+		{
+			name:    "test command substitution",
+			input:   []string{`echo $(echo 'a   b') "$(echo 'a   b')" $(type echo | tr a-z A-Z)`},
+			want:    []string{"a b a   b ECHO IS A SHELL BUILTIN"},
+			wantErr: false,
+		},
+		{
+			name:    "test nested backquotes",
+			input:   []string{"echo `echo \\`echo nested\\``"},
+			want:    []string{"nested"},
+			wantErr: false,
+		},
+		{
+			name:    "test arithmetic expansion",
+			input:   []string{`echo $((1+2*3)) $((2**10)) $((7%3)) $((0x1f)) $((1 ? 4 : 5)) "$(( $(echo 6) / 4 ))"`},
+			want:    []string{"7 1024 1 31 4 1"},
+			wantErr: false,
+		},
+		{
+			name:    "test arithmetic command",
+			input:   []string{"(( 2 > 1 && 3 < 2 )) || let 0 || echo false"},
+			want:    []string{"false"},
+			wantErr: false,
+		},
+		{
+			name:    "test globbing",
+			input:   []string{`echo shell_*.g? "shell"*_test.go 'shell_*' [rs]hell_test.go none*.txt`},
+			want:    []string{"shell_test.go shell_test.go shell_* shell_test.go none*.txt"},
+			wantErr: false,
+		},
+		{
+			name:    "test nullglob",
+			input:   []string{"shopt -s nullglob; echo none*.txt end"},
+			want:    []string{"end"},
+			wantErr: false,
+		},
+		{
+			name:    "test brace expansion",
+			input:   []string{`echo file{,.bak} x{a,{b,c}}y {1..9..4} {08..10} {c..a} "{a,b}" {a}`},
+			want:    []string{"file file.bak xay xby xcy 1 5 9 08 09 10 c b a {a,b} {a}"},
+			wantErr: false,
+		},
+		{
+			name:    "test parameter expansion operators",
+			input:   []string{`export P=/usr/lib/file.tar.gz; echo ${P##*/} ${P%.*} ${P//\//:} ${P:5:3} ${#P} ${P^^} "${UNSET_VAR:-a  b}"`},
+			want:    []string{"file.tar.gz /usr/lib/file.tar :usr:lib:file.tar.gz lib 20 /USR/LIB/FILE.TAR.GZ a  b"},
+			wantErr: false,
+		},
+		{
+			name:    "test parameter expansion error",
+			input:   []string{"echo ${UNSET_VAR:?is required} || echo failed"},
+			want:    []string{"gosh: UNSET_VAR: is required\r\nfailed"},
+			wantErr: false,
+		},
+		{
+			name:    "test variable assignments",
+			input:   []string{`A=1 B="$A x"; GOSH_TMP=tmp sh -c 'echo $GOSH_TMP'; echo "$B [$GOSH_TMP]"`},
+			want:    []string{"tmp\r\n1 x []"},
+			wantErr: false,
+		},
+		{
+			name:    "test exported variables",
+			input:   []string{`GOSH_VAR=1; sh -c 'echo [$GOSH_VAR]'; export GOSH_VAR; sh -c 'echo [$GOSH_VAR]'; unset GOSH_VAR; echo [$GOSH_VAR]`},
+			want:    []string{"[]\r\n[1]\r\n[]"},
+			wantErr: false,
+		},
+		{
+			name:    "test declare and readonly",
+			input:   []string{`declare -i N=2*3; readonly N; N=1; declare -p N`},
+			want:    []string{"gosh: N: readonly variable\r\ndeclare -ir N=\"6\""},
+			wantErr: false,
+		},
+		{
+			name:    "test if elif else",
+			input:   []string{`if false; then echo a; elif test -n x; then echo b; else echo c; fi`},
+			want:    []string{"b"},
+			wantErr: false,
+		},
+		{
+			name:    "test for and while loops",
+			input:   []string{`for i in 1 2 3 4; do if ((i == 2)); then continue; fi; echo $i; done; while true; do echo w; break; done`},
+			want:    []string{"1\r\n3\r\n4\r\nw"},
+			wantErr: false,
+		},
+		{
+			name:    "test arithmetic for loop in a pipeline",
+			input:   []string{`for ((i = 0; i < 3; i++)); do echo $i; done | wc -l`},
+			want:    []string{"3"},
+			wantErr: false,
+		},
+		{
+			name:    "test case",
+			input:   []string{`case main.go in *.txt) echo text;; *.go | *.c) echo code;& x) echo next;; *) echo other;; esac`},
+			want:    []string{"code\r\nnext"},
+			wantErr: false,
+		},
+		{
+			name:    "test functions",
+			input:   []string{`x=global; f() { local x=$1; shift; echo "$x $# $@"; return 3; echo unreachable; }; f a b c; echo $? $x`},
+			want:    []string{"a 2 b c\r\n3 global"},
+			wantErr: false,
+		},
+		{
+			name:    "test type of a function",
+			input:   []string{`function greet { echo hi; }; type greet`},
+			want:    []string{"greet is a function\r\nfunction greet { echo hi; }"},
+			wantErr: false,
+		},
+		{
+			name:    "test non-interactive commands",
+			input:   []string{`$0 -c 'echo "$0 $#"; exit 3; echo unreachable' script a b; echo $?; echo 'echo piped' | $0`},
+			want:    []string{"script 2\r\n3\r\npiped"},
+			wantErr: false,
+		},
+		{
+			name:    "test errexit option",
+			input:   []string{`$0 -e -c 'false || echo tested; false; echo unreachable'; echo $?`},
+			want:    []string{"tested\r\n1"},
+			wantErr: false,
+		},
+		{
+			name:    "test set options",
+			input:   []string{`set -o pipefail -u -- a "b c"; echo $# $2; false | true; echo $?; echo $UNSET_VARIABLE; set -f; echo /*; set +o pipefail +u +f`},
+			want:    []string{"2 b c\r\n1\r\ngosh: UNSET_VARIABLE: unbound variable\r\n/*"},
+			wantErr: false,
+		},
+		{
+			name:    "test xtrace option",
+			input:   []string{`PS4='trace: '; set -x; echo "a b"; set +x`},
+			want:    []string{"trace: echo 'a b'\r\na b\r\ntrace: set +x"},
+			wantErr: false,
+		},
+		{
+			name:    "test traps",
+			input:   []string{`$0 -c 'trap "echo bye" EXIT; trap "echo failed \$?" ERR; false; echo done'`},
+			want:    []string{"failed 1\r\ndone\r\nbye"},
+			wantErr: false,
+		},
+		{
+			name:    "test trap listing",
+			input:   []string{`trap 'echo hup' HUP; trap '' 0; trap -p; trap - HUP EXIT; trap`},
+			want:    []string{"trap -- '' EXIT\r\ntrap -- 'echo hup' SIGHUP"},
+			wantErr: false,
+		},
+		{
+			name:    "test test builtin",
+			input:   []string{`[ -d / ] && test 3 -lt 10 -a abc != abd && echo yes; [ 1 -eq x ]; echo $?`},
+			want:    []string{"yes\r\n[: x: integer expression expected\r\n2"},
+			wantErr: false,
+		},
+		{
+			name:    "test conditional command",
+			input:   []string{`x=v1.22; [[ $x == v1.* && ! -e /missing ]] && [[ $x =~ ^v([0-9]+)\.([0-9]+)$ ]] && echo ${BASH_REMATCH[2]} ${#BASH_REMATCH[@]}`},
+			want:    []string{"22 3"},
+			wantErr: false,
+		},
+		{
+			name:    "test read builtin",
+			input:   []string{`IFS=: read u p rest <<< "root:x:0:0"; echo "$u|$p|$rest"; printf 'a b\nc\n' | { read -a arr; echo ${#arr[@]} ${arr[1]}; }; read x < /dev/null; echo $?`},
+			want:    []string{"root|x|0:0\r\n2 b\r\n1"},
+			wantErr: false,
+		},
+		{
+			name:    "test builtin usage",
+			input:   []string{`cd a b; echo $?; type; echo $?`},
+			want:    []string{"cd: too many arguments\r\n1\r\ntype: usage: type name [name ...]\r\n2"},
+			wantErr: false,
+		},
+		{
+			name:    "test terminal modes restored after a killed command",
+			input:   []string{`modes=$(stty -g); sh -c 'stty raw -echo; kill -TERM $$'; echo $?; test "$modes" = "$(stty -g)" && echo restored`},
+			want:    []string{"Terminated\r\n143\r\nrestored"},
+			wantErr: false,
+		},
+		{
+			name:    "test exec file descriptors",
+			input:   []string{`exec 3>./tmp/exec.txt; echo one >&3; exec 3>&-; echo two >&3; cat ./tmp/exec.txt`},
+			want:    []string{"gosh: 3: bad file descriptor\r\none"},
+			wantErr: false,
+		},
+		{
+			name:    "test exec in a pipeline",
+			input:   []string{`echo piped | exec tr a-z A-Z; echo still running`},
+			want:    []string{"PIPED\r\nstill running"},
+			wantErr: false,
+		},
+		{
+			name:    "test hash builtin",
+			input:   []string{`hash; ls > /dev/null; ls > /dev/null; hash; type ls; hash -r; hash`},
+			want:    []string{"hash: hash table empty\r\nhits\tcommand\r\n   2\t/usr/bin/ls\r\nls is hashed (/usr/bin/ls)\r\nhash: hash table empty"},
+			wantErr: false,
+		},
+		{
+			name:    "test hash pinned path",
+			input:   []string{`hash -p /usr/bin/echo greet; greet hello; hash greet2; echo $?`},
+			want:    []string{"hello\r\nhash: greet2: not found\r\n1"},
+			wantErr: false,
+		},
+		{
+			name:    "test here-document",
+			input:   []string{`printf 'tr a-z A-Z <<EOF\nhello\n  here-doc\nEOF\ncat <<-"EOF"\n\ttabs stripped\n\tEOF\n' | ./tmp/gosh`},
+			want:    []string{"HELLO\r\n  HERE-DOC\r\ntabs stripped"},
+			wantErr: false,
+		},
+		{
+			name:    "test unexported path",
+			input:   []string{`export -n PATH; ls -d /; env | grep -c ^PATH=`},
+			want:    []string{"/\r\n0"},
+			wantErr: false,
+		},
+		{
+			name:    "test unset parameter ends a script",
+			input:   []string{`$0 -c 'set -u; echo $undefined; echo notreached'; $0 -c 'echo ${x:?is required}; echo notreached'; echo status $?`},
+			want:    []string{"gosh: undefined: unbound variable\r\ngosh: x: is required\r\nstatus 1"},
+			wantErr: false,
+		},
+		{
+			name:    "test builtins in a pipeline run in subshells",
+			input:   []string{`exit 3 | cat; export A=1 | cat; B=2 | true; echo "A=$A B=$B"`},
+			want:    []string{"A= B="},
+			wantErr: false,
+		},
+		{
+			name:    "test escaped newline in a script",
+			input:   []string{`printf 'echo one \\\ntwo\necho three \\\n' | ./tmp/gosh`},
+			want:    []string{"one two\r\nthree"},
+			wantErr: false,
+		},
+		{
+			name:    "test field splitting on IFS",
+			input:   []string{`IFS=:; y=1:2::3; set -- $y; echo "$#[$3]"; read a b <<< "1:2:"; echo "$b"; IFS=' :'; set -- $(echo ' a : b  c '); echo $#`},
+			want:    []string{"4[]\r\n2\r\n3"},
+			wantErr: false,
+		},
+		{
+			name:    "test usage of the builtins changing the shell",
+			input:   []string{`set -- a b; shift 1 2; echo "$? $#"; for i in 1 2; do break 1 2; echo "$?"; done`},
+			want:    []string{"shift: too many arguments\r\n1 2\r\nbreak: too many arguments\r\n1\r\nbreak: too many arguments\r\n1"},
+			wantErr: false,
+		},
+		{
+			name:    "test cd in a subshell",
+			input:   []string{`d=$(pwd); x=$(cd / && pwd && ls -d tmp); { cd /; } | cat; f() { cd /; }; f | cat; test "$(pwd)" = "$d" && echo $x`},
+			want:    []string{"/ tmp"},
+			wantErr: false,
+		},
 
 		// TODO: create tests for autocompletion
 		// TODO: create tests for history (up/down arrow)